@@ -0,0 +1,188 @@
+// Package bots tells crawlers, link unfurlers and prefetches apart from
+// people clicking a link
+package bots
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed user_agents.txt
+var defaultUserAgents []byte
+
+//go:embed crawler_rdns.txt
+var defaultRDNSSuffixes []byte
+
+const (
+	// lookupTimeout bounds the reverse DNS lookup of a single click
+	lookupTimeout = time.Second
+	// cacheTTL is how long a reverse DNS answer is reused
+	cacheTTL = 10 * time.Minute
+	// cacheSize caps the reverse DNS cache; it is cleared when full
+	cacheSize = 10000
+)
+
+// Reasons a click is tagged as a bot
+const (
+	ReasonEmptyUserAgent = "empty_user_agent"
+	ReasonPrefetch       = "prefetch"
+	reasonUserAgent      = "user_agent:"
+	reasonReverseDNS     = "reverse_dns:"
+)
+
+// Signals is what a click is classified from, captured from the request
+// while it's being handled so classification can run in the background
+type Signals struct {
+	IP        string
+	UserAgent string
+	Prefetch  bool
+}
+
+// SignalsFromRequest captures the classification signals of a request
+func SignalsFromRequest(req *http.Request, ip string) Signals {
+	return Signals{
+		IP:        ip,
+		UserAgent: req.UserAgent(),
+		Prefetch:  isPrefetch(req.Header),
+	}
+}
+
+// isPrefetch reports whether the browser is fetching the link speculatively,
+// before (or without) the user clicking it
+func isPrefetch(header http.Header) bool {
+	for _, name := range []string{"Purpose", "Sec-Purpose", "X-Purpose", "X-Moz"} {
+		value := strings.ToLower(header.Get(name))
+		if strings.Contains(value, "prefetch") || strings.Contains(value, "prerender") || strings.Contains(value, "preview") {
+			return true
+		}
+	}
+	return false
+}
+
+type cacheEntry struct {
+	names   []string
+	expires time.Time
+}
+
+// Classifier tags clicks as bot traffic. A nil *Classifier only checks the
+// request itself (user agent and prefetch headers) against the built-in list.
+type Classifier struct {
+	userAgents   []string
+	rdnsSuffixes []string
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// Load creates a classifier with the built-in user agent patterns and the
+// reverse DNS suffixes in rdnsPath, or the built-in suffixes when rdnsPath is empty
+func Load(rdnsPath string) (*Classifier, error) {
+	suffixes := defaultRDNSSuffixes
+	if rdnsPath != "" {
+		data, err := os.ReadFile(rdnsPath)
+		if err != nil {
+			return nil, err
+		}
+		suffixes = data
+	}
+
+	// Match whole labels only: "googlebot.com" must not match "evilgooglebot.com"
+	rdnsSuffixes := readList(suffixes)
+	for i, suffix := range rdnsSuffixes {
+		if !strings.HasPrefix(suffix, ".") {
+			rdnsSuffixes[i] = "." + suffix
+		}
+	}
+
+	return &Classifier{
+		userAgents:   readList(defaultUserAgents),
+		rdnsSuffixes: rdnsSuffixes,
+		cache:        make(map[string]cacheEntry),
+	}, nil
+}
+
+// readList parses a list file: one lowercased entry per line, # comments
+func readList(data []byte) []string {
+	var entries []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+	return entries
+}
+
+// Classify returns why a click looks like a bot, or "" if it looks human.
+// The reverse DNS lookup is only made when the cheaper checks pass.
+func (c *Classifier) Classify(ctx context.Context, signals Signals) string {
+	if signals.Prefetch {
+		return ReasonPrefetch
+	}
+
+	ua := strings.ToLower(strings.TrimSpace(signals.UserAgent))
+	if ua == "" {
+		return ReasonEmptyUserAgent
+	}
+
+	patterns := c.patterns()
+	for _, pattern := range patterns {
+		if strings.Contains(ua, pattern) {
+			return reasonUserAgent + pattern
+		}
+	}
+
+	if c == nil || len(c.rdnsSuffixes) == 0 || signals.IP == "" {
+		return ""
+	}
+	for _, name := range c.reverseDNS(ctx, signals.IP) {
+		name = "." + strings.TrimSuffix(strings.ToLower(name), ".")
+		for _, suffix := range c.rdnsSuffixes {
+			if strings.HasSuffix(name, suffix) {
+				return reasonReverseDNS + suffix
+			}
+		}
+	}
+	return ""
+}
+
+var builtinPatterns = sync.OnceValue(func() []string { return readList(defaultUserAgents) })
+
+func (c *Classifier) patterns() []string {
+	if c == nil {
+		return builtinPatterns()
+	}
+	return c.userAgents
+}
+
+// reverseDNS looks up the PTR names of ip, caching answers (including failures)
+func (c *Classifier) reverseDNS(ctx context.Context, ip string) []string {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.cache[ip]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.names
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+	names, _ := net.DefaultResolver.LookupAddr(ctx, ip)
+
+	c.mu.Lock()
+	if len(c.cache) >= cacheSize {
+		clear(c.cache)
+	}
+	c.cache[ip] = cacheEntry{names: names, expires: now.Add(cacheTTL)}
+	c.mu.Unlock()
+	return names
+}
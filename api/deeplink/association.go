@@ -0,0 +1,115 @@
+package deeplink
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// DefaultDomain is the Associations key used for hosts without their own entry
+const DefaultDomain = "*"
+
+// AppAssociation lists the apps allowed to open links of a domain
+type AppAssociation struct {
+	// iOS apps as "<TeamID>.<BundleID>" and the paths they handle
+	AppleAppIDs []string `json:"apple_app_ids"`
+	ApplePaths  []string `json:"apple_paths"`
+	// Android apps
+	Android []AndroidApp `json:"android"`
+}
+
+// AndroidApp identifies an Android app by package and signing certificate
+type AndroidApp struct {
+	PackageName            string   `json:"package_name"`
+	SHA256CertFingerprints []string `json:"sha256_cert_fingerprints"`
+}
+
+// Associations maps a domain (the Host header) to its app association
+type Associations map[string]AppAssociation
+
+// LoadAssociations reads the associations JSON file at path. An empty path
+// means no domain is associated with an app.
+func LoadAssociations(path string) (Associations, error) {
+	if path == "" {
+		return Associations{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read app links config: %w", err)
+	}
+
+	var parsed Associations
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse app links config: %w", err)
+	}
+	// Host names are case-insensitive
+	associations := make(Associations, len(parsed))
+	for host, association := range parsed {
+		associations[strings.ToLower(host)] = association
+	}
+	return associations, nil
+}
+
+// For returns the association of host, falling back to DefaultDomain. host
+// is a Host header, so it may carry a port.
+func (a Associations) For(host string) (AppAssociation, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	if association, ok := a[host]; ok {
+		return association, true
+	}
+	association, ok := a[DefaultDomain]
+	return association, ok
+}
+
+// AppleAppSiteAssociation builds the apple-app-site-association document
+func (a AppAssociation) AppleAppSiteAssociation() map[string]interface{} {
+	paths := a.ApplePaths
+	if len(paths) == 0 {
+		paths = []string{"*"}
+	}
+
+	components := make([]map[string]string, 0, len(paths))
+	for _, path := range paths {
+		components = append(components, map[string]string{"/": path})
+	}
+
+	details := make([]map[string]interface{}, 0, len(a.AppleAppIDs))
+	for _, appID := range a.AppleAppIDs {
+		details = append(details, map[string]interface{}{
+			"appIDs":     []string{appID},
+			"components": components,
+			// Pre-iOS 13 format
+			"appID": appID,
+			"paths": paths,
+		})
+	}
+
+	return map[string]interface{}{
+		"applinks": map[string]interface{}{
+			"apps":    []string{},
+			"details": details,
+		},
+	}
+}
+
+// AssetLinks builds the Digital Asset Links statements served as assetlinks.json
+func (a AppAssociation) AssetLinks() []map[string]interface{} {
+	statements := make([]map[string]interface{}, 0, len(a.Android))
+	for _, app := range a.Android {
+		statements = append(statements, map[string]interface{}{
+			"relation": []string{"delegate_permission/common.handle_all_urls"},
+			"target": map[string]interface{}{
+				"namespace":                "android_app",
+				"package_name":             app.PackageName,
+				"sha256_cert_fingerprints": app.SHA256CertFingerprints,
+			},
+		})
+	}
+	return statements
+}
@@ -0,0 +1,30 @@
+package deeplink
+
+import "testing"
+
+func TestAssociationsFor(t *testing.T) {
+	associations := Associations{
+		"go.example.com": {AppleAppIDs: []string{"TEAM.example"}},
+		DefaultDomain:    {AppleAppIDs: []string{"TEAM.default"}},
+	}
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"go.example.com", "TEAM.example"},
+		{"go.example.com:8443", "TEAM.example"},
+		{"GO.Example.com", "TEAM.example"},
+		{"other.example.com", "TEAM.default"},
+		{"other.example.com:8080", "TEAM.default"},
+	}
+	for _, tt := range tests {
+		association, ok := associations.For(tt.host)
+		if !ok || association.AppleAppIDs[0] != tt.want {
+			t.Errorf("For(%q) = %v, %v, want %s", tt.host, association.AppleAppIDs, ok, tt.want)
+		}
+	}
+
+	if _, ok := (Associations{}).For("go.example.com"); ok {
+		t.Errorf("For on empty associations found an association")
+	}
+}
@@ -0,0 +1,120 @@
+package deeplink
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"shawty-ur/api/useragent"
+)
+
+// Config is the per-link deep-link setup, stored as JSONB on the link. The
+// app URLs may be custom schemes (myapp://item/42) or universal/app links.
+type Config struct {
+	IOS     string `json:"ios,omitempty"`
+	Android string `json:"android,omitempty"`
+}
+
+// AppURL returns the app URL to try for a visitor, if any
+func (c *Config) AppURL(ua useragent.UserAgent) string {
+	if c == nil || ua.Device == useragent.DeviceDesktop {
+		return ""
+	}
+	switch ua.OS {
+	case useragent.OSIOS:
+		return c.IOS
+	case useragent.OSAndroid:
+		return c.Android
+	}
+	return ""
+}
+
+// Validate checks that the app URLs are absolute and not script URLs
+func (c *Config) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.IOS == "" && c.Android == "" {
+		return errors.New("deep_link needs an ios or android url")
+	}
+	for field, raw := range map[string]string{"ios": c.IOS, "android": c.Android} {
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" {
+			return fmt.Errorf("deep_link %s must be an absolute url", field)
+		}
+		switch strings.ToLower(u.Scheme) {
+		case "javascript", "data", "vbscript", "file":
+			return fmt.Errorf("deep_link %s scheme %q is not allowed", field, u.Scheme)
+		}
+	}
+	return nil
+}
+
+// Value implements driver.Valuer so Config can be written to a JSONB column
+func (c *Config) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner so Config can be read from a JSONB column
+func (c *Config) Scan(src any) error {
+	switch data := src.(type) {
+	case nil:
+		*c = Config{}
+		return nil
+	case []byte:
+		return json.Unmarshal(data, c)
+	case string:
+		return json.Unmarshal([]byte(data), c)
+	}
+	return fmt.Errorf("cannot scan %T into deeplink.Config", src)
+}
+
+// bridgeTemplate tries to open the app and falls back to the web URL when
+// the page is still visible shortly after (i.e. the app is not installed)
+var bridgeTemplate = template.Must(template.New("bridge").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Opening…</title>
+</head>
+<body>
+<p>Opening the app… <a href="{{.Fallback}}">Continue in the browser</a></p>
+<script>
+(function () {
+	var fallback = {{.Fallback}};
+	var timer = setTimeout(function () {
+		if (!document.hidden) { window.location.replace(fallback); }
+	}, 1500);
+	document.addEventListener("visibilitychange", function () {
+		if (document.hidden) { clearTimeout(timer); }
+	});
+	window.location.href = {{.AppURL}};
+})();
+</script>
+</body>
+</html>
+`))
+
+// WriteBridge serves the HTML page that opens appURL, falling back to fallbackURL
+func WriteBridge(w http.ResponseWriter, appURL, fallbackURL string) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	return bridgeTemplate.Execute(w, map[string]string{
+		"AppURL":   appURL,
+		"Fallback": fallbackURL,
+	})
+}
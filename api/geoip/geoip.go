@@ -0,0 +1,160 @@
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// Location is the result of an IP lookup. Fields are empty when unknown.
+type Location struct {
+	Country string // ISO 3166-1 alpha-2 code, e.g. "GB"
+	Region  string // ISO 3166-2 code of the first subdivision, e.g. "GB-ENG"
+	City    string // English city name
+}
+
+// record mirrors the parts of a GeoIP2/GeoLite2 City or Country database we use
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// Resolver looks up visitor locations in a local MaxMind-format (.mmdb)
+// database. The file can be replaced while the server is running; Watch picks
+// up the new version. A nil Resolver is valid and resolves nothing, so GeoIP
+// stays optional.
+type Resolver struct {
+	path    string
+	mu      sync.RWMutex
+	reader  *maxminddb.Reader
+	modTime time.Time
+}
+
+// Open loads the database at path. An empty path disables lookups.
+func Open(path string) (*Resolver, error) {
+	if path == "" {
+		slog.Warn("GeoIP database not configured, geo lookups disabled")
+		return nil, nil
+	}
+
+	r := &Resolver{path: path}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reopens the database file and swaps it in for new lookups
+func (r *Resolver) Reload() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat GeoIP database: %w", err)
+	}
+
+	reader, err := maxminddb.Open(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+
+	r.mu.Lock()
+	old := r.reader
+	r.reader = reader
+	r.modTime = info.ModTime()
+	r.mu.Unlock()
+
+	// No lookup can still be using the old reader once the write lock was held
+	if old != nil {
+		old.Close()
+	}
+
+	slog.Info("GeoIP database loaded", "path", r.path, "build_time", reader.Metadata.BuildTime())
+	return nil
+}
+
+// Watch reloads the database whenever the file's modification time changes,
+// checking every interval until ctx is cancelled
+func (r *Resolver) Watch(ctx context.Context, interval time.Duration) {
+	if r == nil || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(r.path)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to stat GeoIP database", "path", r.path, "error", err)
+				continue
+			}
+
+			r.mu.RLock()
+			changed := !info.ModTime().Equal(r.modTime)
+			r.mu.RUnlock()
+
+			if changed {
+				if err := r.Reload(); err != nil {
+					slog.ErrorContext(ctx, "Failed to reload GeoIP database, keeping previous version", "error", err)
+				}
+			}
+		}
+	}
+}
+
+// Lookup returns the location of ip, or an empty Location if it is unknown
+func (r *Resolver) Lookup(ip string) Location {
+	if r == nil {
+		return Location{}
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Location{}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var rec record
+	if err := r.reader.Lookup(addr.Unmap()).Decode(&rec); err != nil {
+		slog.Warn("GeoIP lookup failed", "error", err)
+		return Location{}
+	}
+
+	loc := Location{
+		Country: rec.Country.ISOCode,
+		City:    rec.City.Names["en"],
+	}
+	if len(rec.Subdivisions) > 0 && rec.Subdivisions[0].ISOCode != "" && loc.Country != "" {
+		loc.Region = loc.Country + "-" + rec.Subdivisions[0].ISOCode
+	}
+	return loc
+}
+
+// Close releases the database
+func (r *Resolver) Close() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reader.Close()
+}
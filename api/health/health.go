@@ -0,0 +1,91 @@
+// Package health reports whether the service is alive and ready for
+// traffic: its dependencies answer, its schema is migrated and its
+// background workers keep making progress.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Check statuses
+const (
+	StatusOK       = "ok"
+	StatusFailing  = "failing"  // The dependency is down or out of date
+	StatusStale    = "stale"    // The worker hasn't made progress in a while
+	StatusDisabled = "disabled" // The worker is turned off in the config
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Status    string     `json:"status"`
+	LatencyMS float64    `json:"latency_ms"`
+	Error     string     `json:"error,omitempty"`
+	Version   int64      `json:"version,omitempty"`   // Schema version applied to the database
+	Expected  int64      `json:"expected,omitempty"`  // Latest migration this build ships with
+	LastBeat  *time.Time `json:"last_beat,omitempty"` // When a worker last made progress
+}
+
+// Check inspects one dependency. It must return once ctx is done.
+type Check func(ctx context.Context) Result
+
+// Report is the outcome of all checks
+type Report struct {
+	Status string             `json:"status"`
+	Checks map[string]*Result `json:"checks"`
+}
+
+// Ready reports whether no check is failing or stale
+func (r *Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// Summary is the report without error messages, schema versions or worker
+// beats, for probes that anyone can reach
+func (r *Report) Summary() *Report {
+	summary := &Report{Status: r.Status, Checks: make(map[string]*Result, len(r.Checks))}
+	for name, result := range r.Checks {
+		summary.Checks[name] = &Result{Status: result.Status, LatencyMS: result.LatencyMS}
+	}
+	return summary
+}
+
+// Ping turns a ping function into a check
+func Ping(ping func(ctx context.Context) error) Check {
+	return func(ctx context.Context) Result {
+		if err := ping(ctx); err != nil {
+			return Result{Status: StatusFailing, Error: err.Error()}
+		}
+		return Result{Status: StatusOK}
+	}
+}
+
+// Evaluate runs all checks at once, each with its own timeout
+func Evaluate(ctx context.Context, timeout time.Duration, checks map[string]Check) *Report {
+	report := &Report{Status: StatusOK, Checks: make(map[string]*Result, len(checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			result := check(ctx)
+			result.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = &result
+			if result.Status == StatusFailing || result.Status == StatusStale {
+				report.Status = StatusFailing
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
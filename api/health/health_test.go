@@ -0,0 +1,62 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	ok := func(context.Context) Result { return Result{Status: StatusOK} }
+	disabled := func(context.Context) Result { return Result{Status: StatusDisabled} }
+	stale := func(context.Context) Result { return Result{Status: StatusStale} }
+	failing := Ping(func(context.Context) error { return errors.New("connection refused") })
+	hung := Ping(func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() })
+
+	tests := []struct {
+		name   string
+		checks map[string]Check
+		want   string
+	}{
+		{"all ok", map[string]Check{"postgres": ok, "redis": ok}, StatusOK},
+		{"disabled workers don't count", map[string]Check{"postgres": ok, "worker:rollups": disabled}, StatusOK},
+		{"stale worker", map[string]Check{"postgres": ok, "worker:rollups": stale}, StatusFailing},
+		{"failing dependency", map[string]Check{"postgres": failing, "redis": ok}, StatusFailing},
+		{"hung dependency times out", map[string]Check{"redis": hung}, StatusFailing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Evaluate(context.Background(), 10*time.Millisecond, tt.checks)
+			if report.Status != tt.want {
+				t.Errorf("Evaluate() status = %q, want %q", report.Status, tt.want)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Errorf("Evaluate() has %d checks, want %d", len(report.Checks), len(tt.checks))
+			}
+		})
+	}
+}
+
+func TestReportSummary(t *testing.T) {
+	beat := time.Now()
+	report := &Report{Status: StatusFailing, Checks: map[string]*Result{
+		"redis":          {Status: StatusFailing, LatencyMS: 2000, Error: "dial tcp 10.0.0.7:6379: connection refused"},
+		"migrations":     {Status: StatusOK, LatencyMS: 1.5, Version: 23, Expected: 23},
+		"worker:rollups": {Status: StatusOK, LastBeat: &beat},
+	}}
+
+	summary := report.Summary()
+	if summary.Status != StatusFailing {
+		t.Errorf("Summary() status = %q, want %q", summary.Status, StatusFailing)
+	}
+	for name, result := range summary.Checks {
+		want := Result{Status: report.Checks[name].Status, LatencyMS: report.Checks[name].LatencyMS}
+		if *result != want {
+			t.Errorf("Summary() check %s = %+v, want %+v", name, *result, want)
+		}
+	}
+	if report.Checks["redis"].Error == "" {
+		t.Error("Summary() changed the report it was made from")
+	}
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Heartbeat tracks whether a background worker is still making progress.
+// The worker beats after every unit of work and every idle poll; if it
+// doesn't for staleAfter it is reported stale.
+type Heartbeat struct {
+	name       string
+	staleAfter time.Duration
+	last       atomic.Int64 // Unix nanoseconds of the last beat
+	disabled   atomic.Bool
+}
+
+// NewHeartbeat creates the heartbeat of the worker called name. It counts as
+// a beat, so a worker has staleAfter to get going.
+func NewHeartbeat(name string, staleAfter time.Duration) *Heartbeat {
+	h := &Heartbeat{name: name, staleAfter: staleAfter}
+	h.Beat()
+	return h
+}
+
+// Name is the name of the worker
+func (h *Heartbeat) Name() string {
+	return h.name
+}
+
+// Beat records that the worker made progress
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Disable records that the worker is turned off and won't beat
+func (h *Heartbeat) Disable() {
+	h.disabled.Store(true)
+}
+
+// Check reports the worker disabled, stale or ok
+func (h *Heartbeat) Check(context.Context) Result {
+	if h.disabled.Load() {
+		return Result{Status: StatusDisabled}
+	}
+	last := time.Unix(0, h.last.Load()).UTC()
+	if time.Since(last) > h.staleAfter {
+		return Result{Status: StatusStale, LastBeat: &last}
+	}
+	return Result{Status: StatusOK, LastBeat: &last}
+}
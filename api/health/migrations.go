@@ -0,0 +1,63 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// LatestMigration returns the highest version among the goose migration
+// files in fsys, named like 00021_backfill_country_rollups.sql
+func LatestMigration(fsys fs.FS) (int64, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return 0, err
+	}
+	var latest int64
+	for _, file := range files {
+		prefix, _, _ := strings.Cut(file, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s has no version prefix", file)
+		}
+		latest = max(latest, version)
+	}
+	if latest == 0 {
+		return 0, errors.New("no migrations found")
+	}
+	return latest, nil
+}
+
+// Migrations checks that the schema is at least at the latest version among
+// the migrations in fsys. A newer schema is fine: it is what a rolling
+// deploy of the next release looks like to the replicas still running this
+// one. When the migrations can't be read the check always fails, since
+// there is nothing to compare the schema with.
+func Migrations(version func(ctx context.Context) (int64, error), fsys fs.FS) Check {
+	expected, err := LatestMigration(fsys)
+	if err != nil {
+		err = fmt.Errorf("failed to read embedded migrations: %w", err)
+		return func(context.Context) Result {
+			return Result{Status: StatusFailing, Error: err.Error()}
+		}
+	}
+
+	return func(ctx context.Context) Result {
+		current, err := version(ctx)
+		if err != nil {
+			return Result{Status: StatusFailing, Error: err.Error(), Expected: expected}
+		}
+		if current < expected {
+			return Result{
+				Status:   StatusFailing,
+				Error:    fmt.Sprintf("schema is at version %d, this build needs %d", current, expected),
+				Version:  current,
+				Expected: expected,
+			}
+		}
+		return Result{Status: StatusOK, Version: current, Expected: expected}
+	}
+}
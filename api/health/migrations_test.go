@@ -0,0 +1,42 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMigrations(t *testing.T) {
+	migrated := fstest.MapFS{
+		"00001_create_urls.sql":              {},
+		"00022_add_has_variants_to_urls.sql": {},
+		"migrations.go":                      {},
+	}
+	at := func(v int64) func(context.Context) (int64, error) {
+		return func(context.Context) (int64, error) { return v, nil }
+	}
+	down := func(context.Context) (int64, error) { return 0, errors.New("connection refused") }
+
+	tests := []struct {
+		name    string
+		version func(context.Context) (int64, error)
+		fsys    fstest.MapFS
+		want    string
+	}{
+		{"up to date", at(22), migrated, StatusOK},
+		{"newer schema", at(23), migrated, StatusOK},
+		{"behind", at(21), migrated, StatusFailing},
+		{"database down", down, migrated, StatusFailing},
+		{"no migrations", at(22), fstest.MapFS{}, StatusFailing},
+		{"unreadable migration name", at(22), fstest.MapFS{"add_urls.sql": {}}, StatusFailing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Migrations(tt.version, tt.fsys)(context.Background())
+			if result.Status != tt.want {
+				t.Errorf("Migrations() status = %q (%s), want %q", result.Status, result.Error, tt.want)
+			}
+		})
+	}
+}
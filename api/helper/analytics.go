@@ -0,0 +1,353 @@
+package helper
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"shawty-ur/api/models"
+
+	"github.com/lib/pq"
+)
+
+// AnalyticsStore handles all database operations for click analytics
+type AnalyticsStore struct {
+	Db *sql.DB
+}
+
+// NewAnalyticsStore creates a new analytics store
+func NewAnalyticsStore(db *sql.DB) *AnalyticsStore {
+	return &AnalyticsStore{Db: db}
+}
+
+// clickValueWidth is the width of the url_analytics referrer domain and UTM
+// columns, and of the rollup values they're copied to
+const clickValueWidth = 255
+
+// truncate cuts s to at most n characters, as counted by Postgres
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// RecordClick stores a click event and bumps the link's click counter.
+// Referrer domains and UTM values are truncated to fit their columns, since
+// they come straight from the visitor.
+func (s *AnalyticsStore) RecordClick(ctx context.Context, click *models.Click) error {
+	query := `
+		WITH inserted AS (
+			INSERT INTO url_analytics(url_id, ip_address, user_agent, referrer, country, city, clicked_at,
+				variant_id, via_qr, is_bot, bot_reason, browser, browser_version, os, os_version, device_type,
+				referrer_domain, referrer_category, utm_source, utm_medium, utm_campaign, utm_term, utm_content, ip_hash)
+			VALUES ($1, NULLIF($2, '')::INET, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7,
+				$8, $9, $10, NULLIF($11, ''), NULLIF($12, ''), NULLIF($13, ''), NULLIF($14, ''), NULLIF($15, ''), NULLIF($16, ''),
+				NULLIF($17, ''), NULLIF($18, ''), NULLIF($19, ''), NULLIF($20, ''), NULLIF($21, ''), NULLIF($22, ''), NULLIF($23, ''), NULLIF($24, ''))
+			RETURNING id
+		)
+		UPDATE urls SET clicks = clicks + 1
+		WHERE id = $1
+		RETURNING (SELECT id FROM inserted)
+	`
+
+	err := s.Db.QueryRowContext(
+		ctx,
+		query,
+		click.URLID,
+		click.IPAddress,
+		click.UserAgent,
+		click.Referrer,
+		click.Country,
+		click.City,
+		click.ClickedAt,
+		click.VariantID,
+		click.ViaQR,
+		click.IsBot,
+		click.BotReason,
+		click.Browser,
+		click.BrowserVersion,
+		click.OS,
+		click.OSVersion,
+		click.Device,
+		truncate(click.ReferrerDomain, clickValueWidth),
+		click.ReferrerCategory,
+		truncate(click.UTM.Source, clickValueWidth),
+		truncate(click.UTM.Medium, clickValueWidth),
+		truncate(click.UTM.Campaign, clickValueWidth),
+		truncate(click.UTM.Term, clickValueWidth),
+		truncate(click.UTM.Content, clickValueWidth),
+		click.IPHash,
+	).Scan(&click.ID)
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record click", "error", err, "url_id", click.URLID)
+		return err
+	}
+	return nil
+}
+
+// breakdownLimit caps how many values a breakdown lists
+const breakdownLimit = 10
+
+// statsDimensions are all the dimensions clicks are counted by: the totals,
+// the breakdown columns and the variants
+func statsDimensions() []string {
+	dimensions := []string{""}
+	for _, d := range breakdownDimensions {
+		dimensions = append(dimensions, d.column)
+	}
+	return append(dimensions, dimensionVariant)
+}
+
+// GetLinkStats counts the clicks of a link between from and to, in total,
+// from QR code scans, per device, browser, OS, country, referrer and
+// campaign, per variant, and per bucket of interval. Rolled up hours and
+// days are read from the rollups, so stats keep working after raw clicks are
+// pruned.
+func (s *AnalyticsStore) GetLinkStats(ctx context.Context, link *models.Link, from, to time.Time, interval string) (*models.LinkStats, error) {
+	stats := &models.LinkStats{
+		ShortCode: link.ShortCode,
+		From:      from,
+		To:        to,
+		Interval:  interval,
+		Variants:  []*models.VariantStats{},
+	}
+
+	scope := linkScope(link.ID)
+	counts, err := s.countClicks(ctx, clickQuery{scope: scope, from: from, to: to, dimensions: statsDimensions()})
+	if err != nil {
+		return nil, err
+	}
+	var breakdowns map[string][]*models.Breakdown
+	stats.ClickTotals, breakdowns = summarize(counts)
+
+	stats.Devices = topBreakdown(breakdowns["device_type"])
+	stats.Browsers = topBreakdown(breakdowns["browser"])
+	stats.OS = topBreakdown(breakdowns["os"])
+	stats.Countries = topBreakdown(breakdowns["country"])
+	stats.Referrers = topBreakdown(breakdowns["referrer_domain"])
+	stats.Sources = topBreakdown(breakdowns["referrer_category"])
+	stats.UTMSources = topBreakdown(breakdowns["utm_source"])
+	stats.UTMMediums = topBreakdown(breakdowns["utm_medium"])
+	stats.UTMCampaigns = topBreakdown(breakdowns["utm_campaign"])
+
+	if stats.Series, err = s.clickSeries(ctx, scope, from, to, interval); err != nil {
+		return nil, err
+	}
+
+	variantClicks := map[string]int64{}
+	for _, b := range breakdowns[dimensionVariant] {
+		variantClicks[b.Value] = b.Clicks
+	}
+	variants, err := NewVariantStore(s.Db).ListVariants(ctx, link.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, variant := range variants {
+		stats.Variants = append(stats.Variants, &models.VariantStats{
+			LinkVariant: *variant,
+			Clicks:      variantClicks[strconv.FormatInt(variant.ID, 10)],
+		})
+	}
+
+	return stats, nil
+}
+
+// GetAccountStats counts the clicks of all links of a user, or of a team's
+// links when teamID is set, between from and to: in total and compared to
+// the period of the same length before, per bucket of interval, and for the
+// top links, countries and referrers
+func (s *AnalyticsStore) GetAccountStats(ctx context.Context, userID int64, teamID *int64, from, to time.Time, interval string) (*models.AccountStats, error) {
+	stats := &models.AccountStats{
+		TeamID:   teamID,
+		From:     from,
+		To:       to,
+		Interval: interval,
+	}
+
+	scope := userScope(userID)
+	if teamID != nil {
+		scope = teamScope(*teamID)
+	}
+
+	counts, err := s.countClicks(ctx, clickQuery{
+		scope:      scope,
+		from:       from,
+		to:         to,
+		dimensions: []string{"", "country", "referrer_domain", "referrer_category"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var breakdowns map[string][]*models.Breakdown
+	stats.ClickTotals, breakdowns = summarize(counts)
+	stats.TopCountries = topBreakdown(breakdowns["country"])
+	stats.TopReferrers = topBreakdown(breakdowns["referrer_domain"])
+	stats.TopSources = topBreakdown(breakdowns["referrer_category"])
+
+	previous, err := s.countClicks(ctx, clickQuery{scope: scope, from: from.Add(-to.Sub(from)), to: from, dimensions: []string{""}})
+	if err != nil {
+		return nil, err
+	}
+	stats.Previous, _ = summarize(previous)
+	stats.Growth = models.ClickGrowth{
+		Clicks:      growth(stats.Previous.Clicks, stats.Clicks),
+		HumanClicks: growth(stats.Previous.HumanClicks, stats.HumanClicks),
+	}
+
+	if stats.Series, err = s.clickSeries(ctx, scope, from, to, interval); err != nil {
+		return nil, err
+	}
+
+	perLink, err := s.countClicks(ctx, clickQuery{scope: scope, from: from, to: to, dimensions: []string{""}, perLink: true})
+	if err != nil {
+		return nil, err
+	}
+	if stats.TopLinks, err = s.topLinks(ctx, perLink); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// summarize adds up click counts into totals and human clicks per value of
+// every other dimension. Variant breakdowns are keyed by variant id.
+func summarize(counts []dimensionCount) (models.ClickTotals, map[string][]*models.Breakdown) {
+	missing := map[string]string{}
+	for _, d := range breakdownDimensions {
+		missing[d.column] = d.missing
+	}
+
+	var totals models.ClickTotals
+	breakdowns := map[string][]*models.Breakdown{}
+	for _, c := range counts {
+		human := c.clicks - c.botClicks
+		if c.dimension == "" {
+			totals.Clicks += c.clicks
+			totals.BotClicks += c.botClicks
+			totals.HumanClicks += human
+			totals.QRScans += c.qrClicks
+			continue
+		}
+		if human == 0 {
+			continue
+		}
+		value := c.value
+		if value == "" {
+			value = missing[c.dimension]
+		}
+		breakdowns[c.dimension] = append(breakdowns[c.dimension], &models.Breakdown{Value: value, Clicks: human})
+	}
+	return totals, breakdowns
+}
+
+// topBreakdown sorts a breakdown by clicks, most first, and keeps the top breakdownLimit
+func topBreakdown(breakdown []*models.Breakdown) []*models.Breakdown {
+	if breakdown == nil {
+		return []*models.Breakdown{}
+	}
+	slices.SortFunc(breakdown, func(a, b *models.Breakdown) int {
+		if c := cmp.Compare(b.Clicks, a.Clicks); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Value, b.Value)
+	})
+	return breakdown[:min(len(breakdown), breakdownLimit)]
+}
+
+// growth is the change from previous to current in percent, or nil when
+// there is nothing to compare to
+func growth(previous, current int64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	pct := float64(current-previous) / float64(previous) * 100
+	return &pct
+}
+
+// clickSeries counts clicks per bucket of interval between from and to,
+// with a zero point for every empty bucket
+func (s *AnalyticsStore) clickSeries(ctx context.Context, scope clickScope, from, to time.Time, interval string) ([]*models.SeriesPoint, error) {
+	counts, err := s.countClicks(ctx, clickQuery{scope: scope, from: from, to: to, dimensions: []string{""}, interval: interval})
+	if err != nil {
+		return nil, err
+	}
+
+	step := time.Hour
+	if interval == IntervalDay {
+		step = 24 * time.Hour
+	}
+	byBucket := map[time.Time]dimensionCount{}
+	for _, c := range counts {
+		byBucket[c.bucket.UTC()] = c
+	}
+
+	series := []*models.SeriesPoint{}
+	for bucket := from.UTC().Truncate(step); bucket.Before(to); bucket = bucket.Add(step) {
+		c := byBucket[bucket]
+		series = append(series, &models.SeriesPoint{
+			Bucket:      bucket,
+			Clicks:      c.clicks,
+			HumanClicks: c.clicks - c.botClicks,
+		})
+	}
+	return series, nil
+}
+
+// topLinks loads the links with the most human clicks from per-link totals
+func (s *AnalyticsStore) topLinks(ctx context.Context, counts []dimensionCount) ([]*models.LinkClicks, error) {
+	slices.SortFunc(counts, func(a, b dimensionCount) int {
+		if c := cmp.Compare(b.clicks-b.botClicks, a.clicks-a.botClicks); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.urlID, b.urlID)
+	})
+	counts = counts[:min(len(counts), breakdownLimit)]
+
+	ids := make([]int64, len(counts))
+	for i, c := range counts {
+		ids[i] = c.urlID
+	}
+
+	query := `
+		SELECT u.id, u.short_code, COALESCE(d.hostname, ''), u.original_url
+		FROM urls u
+		LEFT JOIN domains d ON d.id = u.domain_id
+		WHERE u.id = ANY($1)
+	`
+	rows, err := s.Db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load top links", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := map[int64]*models.LinkClicks{}
+	for rows.Next() {
+		link := &models.LinkClicks{}
+		if err := rows.Scan(&link.ID, &link.ShortCode, &link.Domain, &link.OriginalURL); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan top link row", "error", err)
+			return nil, err
+		}
+		byID[link.ID] = link
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	links := []*models.LinkClicks{}
+	for _, c := range counts {
+		if link, ok := byID[c.urlID]; ok && c.clicks > c.botClicks {
+			link.Clicks = c.clicks - c.botClicks
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
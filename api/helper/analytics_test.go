@@ -0,0 +1,34 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		n    int
+		want string
+	}{
+		{"short", "newsletter", clickValueWidth, "newsletter"},
+		{"empty", "", clickValueWidth, ""},
+		{"exact", strings.Repeat("a", clickValueWidth), clickValueWidth, strings.Repeat("a", clickValueWidth)},
+		{"over-long utm value", strings.Repeat("a", 300), clickValueWidth, strings.Repeat("a", clickValueWidth)},
+		{"multibyte", strings.Repeat("é", 300), clickValueWidth, strings.Repeat("é", clickValueWidth)},
+		{"cut between characters", "aé日本", 3, "aé日"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncate(tt.in, tt.n)
+			if got != tt.want {
+				t.Errorf("truncate() = %q, want %q", got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncate() = %q, not valid UTF-8", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package helper
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// CertCache is an autocert.Cache backed by Postgres, so every replica serves
+// the same certificates and only one of them has to obtain each one
+type CertCache struct {
+	Db *sql.DB
+}
+
+// NewCertCache creates a new certificate cache
+func NewCertCache(db *sql.DB) *CertCache {
+	return &CertCache{Db: db}
+}
+
+var _ autocert.Cache = (*CertCache)(nil)
+
+// Get returns the cached data for key, or autocert.ErrCacheMiss
+func (c *CertCache) Get(ctx context.Context, key string) ([]byte, error) {
+	var data []byte
+	err := c.Db.QueryRowContext(ctx, `SELECT data FROM acme_cache WHERE key = $1`, key).Scan(&data)
+
+	if err == sql.ErrNoRows {
+		return nil, autocert.ErrCacheMiss
+	}
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get ACME cache entry", "error", err, "key", key)
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Put stores data under key, replacing any previous value
+func (c *CertCache) Put(ctx context.Context, key string, data []byte) error {
+	query := `
+		INSERT INTO acme_cache(key, data) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET data = EXCLUDED.data, updated_at = NOW()
+	`
+
+	if _, err := c.Db.ExecContext(ctx, query, key, data); err != nil {
+		slog.ErrorContext(ctx, "Failed to put ACME cache entry", "error", err, "key", key)
+		return err
+	}
+	return nil
+}
+
+// Delete removes key from the cache
+func (c *CertCache) Delete(ctx context.Context, key string) error {
+	if _, err := c.Db.ExecContext(ctx, `DELETE FROM acme_cache WHERE key = $1`, key); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete ACME cache entry", "error", err, "key", key)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// schemePlaceholder is added to destinations stored without a scheme (shorten
+// accepts "example.com/path") so they can be parsed, and stripped again after
+const schemePlaceholder = "http://"
+
+// parseDestination parses a stored destination URL and returns a function that
+// formats it back in the same form it came in
+func parseDestination(rawURL string) (*url.URL, func(*url.URL) string, error) {
+	hasScheme := strings.Contains(rawURL, "://")
+	if !hasScheme {
+		rawURL = schemePlaceholder + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid destination url: %w", err)
+	}
+
+	format := func(u *url.URL) string {
+		if hasScheme {
+			return u.String()
+		}
+		return strings.TrimPrefix(u.String(), schemePlaceholder)
+	}
+	return u, format, nil
+}
+
+// queryKey returns the unescaped key of a single raw key=value query parameter
+func queryKey(param string) string {
+	key, _, _ := strings.Cut(param, "=")
+	if unescaped, err := url.QueryUnescape(key); err == nil {
+		return unescaped
+	}
+	return key
+}
+
+// splitQuery splits a raw query string into its raw key=value parameters
+func splitQuery(rawQuery string) []string {
+	if rawQuery == "" {
+		return nil
+	}
+	return strings.Split(rawQuery, "&")
+}
+
+// ErrInvalidPassthroughPath is returned for path suffixes that would escape the destination path
+var ErrInvalidPassthroughPath = errors.New("invalid passthrough path")
+
+// AppendPassthrough forwards the parts of the short-link request that follow
+// the code to the destination. pathSuffix is the escaped path after
+// "/{code}/" and is appended to the destination path; rawQuery is the
+// visitor's query string, merged into the destination's. When both define a
+// key the destination's value wins, so visitors can't override parameters the
+// owner set (e.g. UTM tags). Either argument may be empty.
+func AppendPassthrough(destination, pathSuffix, rawQuery string) (string, error) {
+	if pathSuffix == "" && rawQuery == "" {
+		return destination, nil
+	}
+
+	u, format, err := parseDestination(destination)
+	if err != nil {
+		return "", err
+	}
+
+	if pathSuffix != "" {
+		// Check the unescaped path so "%2F.." can't reach a parent either
+		unescaped, err := url.PathUnescape(pathSuffix)
+		if err != nil {
+			return "", ErrInvalidPassthroughPath
+		}
+		for _, segment := range strings.Split(unescaped, "/") {
+			if segment == "." || segment == ".." {
+				return "", ErrInvalidPassthroughPath
+			}
+		}
+
+		escaped := strings.TrimSuffix(u.EscapedPath(), "/") + "/" + pathSuffix
+		path, err := url.PathUnescape(escaped)
+		if err != nil {
+			return "", ErrInvalidPassthroughPath
+		}
+		u.Path = path
+		u.RawPath = escaped
+	}
+
+	if rawQuery != "" {
+		params := splitQuery(u.RawQuery)
+		existing := make(map[string]bool, len(params))
+		for _, param := range params {
+			existing[queryKey(param)] = true
+		}
+		for _, param := range splitQuery(rawQuery) {
+			if param == "" || existing[queryKey(param)] {
+				continue
+			}
+			params = append(params, param)
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+
+	return format(u), nil
+}
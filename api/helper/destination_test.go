@@ -0,0 +1,51 @@
+package helper
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAppendPassthrough(t *testing.T) {
+	tests := []struct {
+		name        string
+		destination string
+		pathSuffix  string
+		rawQuery    string
+		want        string
+		wantErr     error
+	}{
+		{"nothing to forward", "https://example.com/docs?a=1#top", "", "", "https://example.com/docs?a=1#top", nil},
+		{"joins path", "https://example.com/docs", "guide/intro", "", "https://example.com/docs/guide/intro", nil},
+		{"joins after trailing slash", "https://example.com/docs/", "guide", "", "https://example.com/docs/guide", nil},
+		{"joins onto bare host", "https://example.com", "guide", "", "https://example.com/guide", nil},
+		{"keeps trailing slash", "https://example.com/docs", "guide/", "", "https://example.com/docs/guide/", nil},
+		{"keeps escaped characters", "https://example.com/docs", "a%20b/%C3%A9", "", "https://example.com/docs/a%20b/%C3%A9", nil},
+		{"keeps encoded slash", "https://example.com/files", "a%2Fb", "", "https://example.com/files/a%2Fb", nil},
+		{"rejects parent segment", "https://example.com/docs", "../admin", "", "", ErrInvalidPassthroughPath},
+		{"rejects inner parent segment", "https://example.com/docs", "a/../../admin", "", "", ErrInvalidPassthroughPath},
+		{"rejects dot segment", "https://example.com/docs", "./a", "", "", ErrInvalidPassthroughPath},
+		{"rejects encoded parent segment", "https://example.com/docs", "%2E%2E/admin", "", "", ErrInvalidPassthroughPath},
+		{"rejects parent behind encoded slash", "https://example.com/docs", "a%2F..%2F..%2Fadmin", "", "", ErrInvalidPassthroughPath},
+		{"rejects bad escape", "https://example.com/docs", "a%zz", "", "", ErrInvalidPassthroughPath},
+		{"adds query", "https://example.com/docs", "", "ref=tw", "https://example.com/docs?ref=tw", nil},
+		{"merges with destination query", "https://example.com/docs?a=1", "", "ref=tw&b=%2F", "https://example.com/docs?a=1&ref=tw&b=%2F", nil},
+		{"destination value wins", "https://example.com/?utm_source=mail&a=1", "", "utm_source=spam&b=2", "https://example.com/?utm_source=mail&a=1&b=2", nil},
+		{"destination wins over escaped key", "https://example.com/?utm_source=mail", "", "utm%5Fsource=spam", "https://example.com/?utm_source=mail", nil},
+		{"keeps visitor duplicate keys", "https://example.com/", "", "tag=a&tag=b", "https://example.com/?tag=a&tag=b", nil},
+		{"drops visitor duplicates of destination keys", "https://example.com/?tag=a&tag=b", "", "tag=c", "https://example.com/?tag=a&tag=b", nil},
+		{"skips empty params", "https://example.com/", "", "a=1&&b=2", "https://example.com/?a=1&b=2", nil},
+		{"keeps fragment", "https://example.com/docs?a=1#top", "guide", "b=2", "https://example.com/docs/guide?a=1&b=2#top", nil},
+		{"no scheme", "example.com/docs", "guide", "b=2", "example.com/docs/guide?b=2", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AppendPassthrough(tt.destination, tt.pathSuffix, tt.rawQuery)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AppendPassthrough() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("AppendPassthrough() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
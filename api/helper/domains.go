@@ -0,0 +1,242 @@
+package helper
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"shawty-ur/api/models"
+	"shawty-ur/api/utils/db"
+
+	"github.com/lib/pq"
+)
+
+// Domain verification: the team publishes a TXT record
+// "<DomainVerificationPrefix>.<hostname>" with value "<DomainVerificationValue><token>"
+const (
+	DomainVerificationPrefix = "_shawty-verify"
+	DomainVerificationValue  = "shawty-verify="
+)
+
+// DomainClaimTTL is how long a registered domain may stay unverified. Older
+// claims lapse and no longer count against anyone registering the hostname.
+const DomainClaimTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrDomainTaken is returned when the hostname was verified by another
+	// team, or is already registered by the same one
+	ErrDomainTaken = errors.New("domain is already registered")
+	// ErrDomainClaimExpired is returned by VerifyDomain for claims older than
+	// DomainClaimTTL
+	ErrDomainClaimExpired = errors.New("domain claim expired, register the domain again")
+)
+
+// DomainStore handles all database operations for branded domains
+type DomainStore struct {
+	Db *sql.DB
+}
+
+// NewDomainStore creates a new domain store
+func NewDomainStore(db *sql.DB) *DomainStore {
+	return &DomainStore{Db: db}
+}
+
+const domainColumns = `id, team_id, hostname, verification_token, verified_at, created_at, updated_at`
+
+func scanDomain(row rowScanner, domain *models.Domain) error {
+	return row.Scan(
+		&domain.ID,
+		&domain.TeamID,
+		&domain.Hostname,
+		&domain.VerificationToken,
+		&domain.VerifiedAt,
+		&domain.CreatedAt,
+		&domain.UpdatedAt,
+	)
+}
+
+// NormalizeHostname lowercases a hostname and strips any port and trailing dot
+func NormalizeHostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// CreateDomain registers an unverified domain for a team. Other teams'
+// unverified claims on the hostname don't stand in the way; ErrDomainTaken is
+// returned when it's verified already or the team has claimed it before.
+func (s *DomainStore) CreateDomain(ctx context.Context, domain *models.Domain) error {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	domain.VerificationToken = hex.EncodeToString(token)
+
+	return db.WithTx(s.Db, ctx, func(tx *sql.Tx) error {
+		// Lapsed claims are dropped here rather than by a background job
+		query := `DELETE FROM domains WHERE verified_at IS NULL AND created_at < $1`
+		if _, err := tx.ExecContext(ctx, query, time.Now().Add(-DomainClaimTTL)); err != nil {
+			slog.ErrorContext(ctx, "Failed to delete expired domain claims", "error", err)
+			return err
+		}
+
+		query = `
+			INSERT INTO domains(team_id, hostname, verification_token)
+			SELECT $1, $2, $3
+			WHERE NOT EXISTS (
+				SELECT 1 FROM domains WHERE hostname = $2 AND (verified_at IS NOT NULL OR team_id = $1)
+			)
+			RETURNING id, created_at, updated_at
+		`
+		err := tx.QueryRowContext(ctx, query, domain.TeamID, domain.Hostname, domain.VerificationToken).
+			Scan(&domain.ID, &domain.CreatedAt, &domain.UpdatedAt)
+		var pqErr *pq.Error
+		if err == sql.ErrNoRows || (errors.As(err, &pqErr) && pqErr.Code == uniqueViolation) {
+			return ErrDomainTaken
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to create domain", "error", err, "hostname", domain.Hostname)
+			return err
+		}
+
+		slog.InfoContext(ctx, "Domain created", "id", domain.ID, "hostname", domain.Hostname, "team_id", domain.TeamID)
+		return nil
+	})
+}
+
+// GetVerifiedDomain retrieves a domain by hostname only if it passed verification
+func (s *DomainStore) GetVerifiedDomain(ctx context.Context, hostname string) (*models.Domain, error) {
+	query := `SELECT ` + domainColumns + ` FROM domains WHERE hostname = $1 AND verified_at IS NOT NULL`
+
+	domain := &models.Domain{}
+	err := scanDomain(s.Db.QueryRowContext(ctx, query, NormalizeHostname(hostname)), domain)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get verified domain", "error", err, "hostname", hostname)
+		return nil, err
+	}
+
+	return domain, nil
+}
+
+// GetDomainByID retrieves a domain by ID
+func (s *DomainStore) GetDomainByID(ctx context.Context, id int64) (*models.Domain, error) {
+	query := `SELECT ` + domainColumns + ` FROM domains WHERE id = $1`
+
+	domain := &models.Domain{}
+	err := scanDomain(s.Db.QueryRowContext(ctx, query, id), domain)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get domain by id", "error", err, "id", id)
+		return nil, err
+	}
+
+	return domain, nil
+}
+
+// ListDomainsForUser retrieves the domains of every team the user belongs to,
+// leaving out lapsed claims
+func (s *DomainStore) ListDomainsForUser(ctx context.Context, userID int64) ([]*models.Domain, error) {
+	query := `
+		SELECT ` + prefixColumns("d", domainColumns) + `
+		FROM domains d
+		JOIN team_members m ON m.team_id = d.team_id
+		WHERE m.user_id = $1 AND (d.verified_at IS NOT NULL OR d.created_at >= $2)
+		ORDER BY d.hostname
+	`
+
+	rows, err := s.Db.QueryContext(ctx, query, userID, time.Now().Add(-DomainClaimTTL))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list domains", "error", err, "user_id", userID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	domains := []*models.Domain{}
+	for rows.Next() {
+		domain := &models.Domain{}
+		if err := scanDomain(rows, domain); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan domain row", "error", err)
+			return nil, err
+		}
+		domains = append(domains, domain)
+	}
+
+	return domains, rows.Err()
+}
+
+// VerifyDomain checks the domain's DNS TXT record and marks it verified if the
+// token is published. Other teams' unverified claims on the hostname are
+// deleted, since they can no longer be verified.
+func (s *DomainStore) VerifyDomain(ctx context.Context, domain *models.Domain) error {
+	if time.Since(domain.CreatedAt) > DomainClaimTTL {
+		return ErrDomainClaimExpired
+	}
+
+	name := DomainVerificationPrefix + "." + domain.Hostname
+	records, err := net.DefaultResolver.LookupTXT(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to look up TXT record %s: %w", name, err)
+	}
+
+	expected := DomainVerificationValue + domain.VerificationToken
+	found := false
+	for _, record := range records {
+		if strings.TrimSpace(record) == expected {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("TXT record %s does not contain %q", name, expected)
+	}
+
+	return db.WithTx(s.Db, ctx, func(tx *sql.Tx) error {
+		query := `DELETE FROM domains WHERE hostname = $1 AND id <> $2 AND verified_at IS NULL`
+		result, err := tx.ExecContext(ctx, query, domain.Hostname, domain.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to delete competing domain claims", "error", err, "hostname", domain.Hostname)
+			return err
+		}
+
+		query = `UPDATE domains SET verified_at = NOW(), updated_at = NOW() WHERE id = $1 RETURNING verified_at, updated_at`
+		err = tx.QueryRowContext(ctx, query, domain.ID).Scan(&domain.VerifiedAt, &domain.UpdatedAt)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return ErrDomainTaken
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to mark domain verified", "error", err, "id", domain.ID)
+			return err
+		}
+
+		removed, _ := result.RowsAffected()
+		slog.InfoContext(ctx, "Domain verified", "id", domain.ID, "hostname", domain.Hostname, "removed_claims", removed)
+		return nil
+	})
+}
+
+// DeleteDomain removes a domain along with its links
+func (s *DomainStore) DeleteDomain(ctx context.Context, id int64) error {
+	if _, err := s.Db.ExecContext(ctx, `DELETE FROM domains WHERE id = $1`, id); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete domain", "error", err, "id", id)
+		return err
+	}
+	return nil
+}
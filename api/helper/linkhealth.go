@@ -0,0 +1,100 @@
+package helper
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"shawty-ur/api/models"
+)
+
+// ClaimHealthChecks picks up to limit unexpired links that are due for a
+// destination check and moves their next check lease into the future, so
+// other replicas running the monitor skip them in the meantime
+func (s *LinkStore) ClaimHealthChecks(ctx context.Context, limit int, lease time.Duration) ([]*models.Link, error) {
+	query := `
+		UPDATE urls SET health_next_check_at = NOW() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM urls
+			WHERE health_next_check_at <= NOW()
+			AND (expires_at IS NULL OR expires_at > NOW())
+			ORDER BY health_next_check_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + linkColumns
+
+	rows, err := s.Db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to claim link health checks", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*models.Link{}
+	for rows.Next() {
+		link := &models.Link{}
+		if err := scanLink(rows, link); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan link row", "error", err)
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// RecordHealth stores the outcome of a destination check and when to check next
+func (s *LinkStore) RecordHealth(ctx context.Context, id int64, health models.LinkHealth, nextCheck time.Time) error {
+	query := `
+		UPDATE urls SET
+			health_status_code = $1,
+			health_latency_ms = $2,
+			health_error = $3,
+			health_checked_at = $4,
+			health_failures = $5,
+			broken = $6,
+			health_next_check_at = $7
+		WHERE id = $8
+	`
+
+	_, err := s.Db.ExecContext(ctx, query,
+		health.StatusCode,
+		health.LatencyMs,
+		health.Error,
+		health.CheckedAt,
+		health.Failures,
+		health.Broken,
+		nextCheck,
+		id,
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record link health", "error", err, "id", id)
+		return err
+	}
+	return nil
+}
+
+// ListBrokenLinks retrieves the links owned by userID that are flagged broken
+func (s *LinkStore) ListBrokenLinks(ctx context.Context, userID int64) ([]*models.Link, error) {
+	query := `SELECT ` + linkColumns + ` FROM urls WHERE user_id = $1 AND broken ORDER BY clicks DESC, health_checked_at DESC`
+
+	rows, err := s.Db.QueryContext(ctx, query, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list broken links", "error", err, "user_id", userID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*models.Link{}
+	for rows.Next() {
+		link := &models.Link{}
+		if err := scanLink(rows, link); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan link row", "error", err)
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
@@ -0,0 +1,284 @@
+package helper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"shawty-ur/api/models"
+
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the Postgres error code for a unique constraint violation
+const uniqueViolation = "23505"
+
+// ErrShortCodeTaken is returned by CreateLink when the short code is already
+// in use on the link's domain
+var ErrShortCodeTaken = errors.New("short code is already taken")
+
+// LinkStore handles all database operations for links
+type LinkStore struct {
+	Db *sql.DB
+}
+
+// NewLinkStore creates a new link store
+func NewLinkStore(db *sql.DB) *LinkStore {
+	return &LinkStore{Db: db}
+}
+
+// linkColumns is the column list scanned by scanLink, in order
+const linkColumns = `id, user_id, domain_id, original_url, short_code, custom_short, clicks,
+	title, tags, notes, expires_at, forward_query, forward_path, routing_rules,
+	deep_link, health_status_code, health_latency_ms, health_error, health_checked_at,
+	health_failures, broken, privacy_mode, has_variants, created_at, updated_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLink(row rowScanner, link *models.Link, extra ...any) error {
+	dest := []any{
+		&link.ID,
+		&link.UserID,
+		&link.DomainID,
+		&link.OriginalURL,
+		&link.ShortCode,
+		&link.CustomShort,
+		&link.Clicks,
+		&link.Title,
+		pq.Array(&link.Tags),
+		&link.Notes,
+		&link.ExpiresAt,
+		&link.ForwardQuery,
+		&link.ForwardPath,
+		&link.RoutingRules,
+		&link.DeepLink,
+		&link.Health.StatusCode,
+		&link.Health.LatencyMs,
+		&link.Health.Error,
+		&link.Health.CheckedAt,
+		&link.Health.Failures,
+		&link.Health.Broken,
+		&link.PrivacyMode,
+		&link.HasVariants,
+		&link.CreatedAt,
+		&link.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// CreateLink inserts a new link
+func (s *LinkStore) CreateLink(ctx context.Context, link *models.Link) error {
+	if link.Tags == nil {
+		link.Tags = []string{}
+	}
+
+	query := `
+		INSERT INTO urls(user_id, domain_id, original_url, short_code, custom_short, tags, notes, expires_at,
+			forward_query, forward_path, routing_rules, deep_link, privacy_mode)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := s.Db.QueryRowContext(
+		ctx,
+		query,
+		link.UserID,
+		link.DomainID,
+		link.OriginalURL,
+		link.ShortCode,
+		link.CustomShort,
+		pq.Array(link.Tags),
+		link.Notes,
+		link.ExpiresAt,
+		link.ForwardQuery,
+		link.ForwardPath,
+		link.RoutingRules,
+		link.DeepLink,
+		link.PrivacyMode,
+	).Scan(&link.ID, &link.CreatedAt, &link.UpdatedAt)
+
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return ErrShortCodeTaken
+		}
+		slog.ErrorContext(ctx, "Failed to create link", "error", err, "short_code", link.ShortCode)
+		return err
+	}
+
+	slog.InfoContext(ctx, "Link created", "id", link.ID, "short_code", link.ShortCode)
+	return nil
+}
+
+// GetLinkByCode retrieves a link by its short code on a domain; a nil
+// domainID means the default domain
+func (s *LinkStore) GetLinkByCode(ctx context.Context, domainID *int64, code string) (*models.Link, error) {
+	query := `SELECT ` + linkColumns + ` FROM urls WHERE COALESCE(domain_id, 0) = COALESCE($1, 0) AND short_code = $2`
+
+	link := &models.Link{}
+	err := scanLink(s.Db.QueryRowContext(ctx, query, domainID, code), link)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get link by code", "error", err, "short_code", code)
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// UpdateLinkTitle stores the fetched title of the destination page
+func (s *LinkStore) UpdateLinkTitle(ctx context.Context, id int64, title string) error {
+	query := `UPDATE urls SET title = $1, updated_at = NOW() WHERE id = $2`
+
+	if _, err := s.Db.ExecContext(ctx, query, title, id); err != nil {
+		slog.ErrorContext(ctx, "Failed to update link title", "error", err, "id", id)
+		return err
+	}
+	return nil
+}
+
+// UpdateLink saves the editable fields of a link: destination, tags and notes
+func (s *LinkStore) UpdateLink(ctx context.Context, link *models.Link) error {
+	if link.Tags == nil {
+		link.Tags = []string{}
+	}
+
+	query := `
+		UPDATE urls SET original_url = $1, tags = $2, notes = $3, privacy_mode = $4, updated_at = NOW()
+		WHERE id = $5
+		RETURNING updated_at
+	`
+
+	err := s.Db.QueryRowContext(ctx, query, link.OriginalURL, pq.Array(link.Tags), link.Notes, link.PrivacyMode, link.ID).Scan(&link.UpdatedAt)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to update link", "error", err, "id", link.ID)
+		return err
+	}
+	return nil
+}
+
+// DeleteLink removes a link along with its variants and click history
+func (s *LinkStore) DeleteLink(ctx context.Context, id int64) error {
+	if _, err := s.Db.ExecContext(ctx, `DELETE FROM urls WHERE id = $1`, id); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete link", "error", err, "id", id)
+		return err
+	}
+	return nil
+}
+
+// ClaimExpiredLinks marks up to limit owned links that have expired since
+// the last call as notified and returns them, so each expiry is reported once
+func (s *LinkStore) ClaimExpiredLinks(ctx context.Context, limit int) ([]*models.Link, error) {
+	query := `
+		UPDATE urls SET expired_notified = TRUE
+		WHERE id IN (
+			SELECT id FROM urls
+			WHERE expires_at <= NOW() AND NOT expired_notified AND user_id IS NOT NULL
+			ORDER BY expires_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + linkColumns
+
+	rows, err := s.Db.QueryContext(ctx, query, limit)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to claim expired links", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*models.Link{}
+	for rows.Next() {
+		link := &models.Link{}
+		if err := scanLink(rows, link); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan link row", "error", err)
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// SearchLinks runs a ranked search over the links owned by userID and the
+// links on the domains of their teams.
+// Full-text matches on the search vector are ranked with ts_rank_cd, and
+// trigram similarity catches partial matches (e.g. "examp" or half a code)
+// that the tokenizer would miss. An empty query lists the newest links.
+func (s *LinkStore) SearchLinks(ctx context.Context, userID int64, q string, limit, offset int) ([]*models.LinkSearchResult, int, error) {
+	q = strings.TrimSpace(q)
+
+	query := `
+		WITH q AS (SELECT websearch_to_tsquery('simple', $2) AS tsq)
+		SELECT ` + prefixColumns("u", linkColumns) + `,
+			ts_rank_cd(u.search_vector, q.tsq) + GREATEST(
+				similarity(u.short_code, $2),
+				word_similarity($2, u.original_url),
+				word_similarity($2, COALESCE(u.title, ''))
+			) AS rank,
+			COUNT(*) OVER() AS total
+		FROM urls u, q
+		WHERE (
+			u.user_id = $1
+			OR u.domain_id IN (
+				SELECT d.id FROM domains d
+				JOIN team_members tm ON tm.team_id = d.team_id
+				WHERE tm.user_id = $1
+			)
+		)
+		AND (
+			$2 = ''
+			OR u.search_vector @@ q.tsq
+			OR u.short_code ILIKE $3
+			OR u.original_url ILIKE $3
+			OR u.title ILIKE $3
+			OR u.notes ILIKE $3
+			OR EXISTS (SELECT 1 FROM unnest(u.tags) AS t WHERE t ILIKE $3)
+		)
+		ORDER BY rank DESC, u.created_at DESC
+		LIMIT $4 OFFSET $5
+	`
+
+	rows, err := s.Db.QueryContext(ctx, query, userID, q, "%"+escapeLike(q)+"%", limit, offset)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to search links", "error", err, "user_id", userID)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	total := 0
+	results := []*models.LinkSearchResult{}
+	for rows.Next() {
+		result := &models.LinkSearchResult{}
+		if err := scanLink(rows, &result.Link, &result.Rank, &total); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan link row", "error", err)
+			return nil, 0, err
+		}
+		results = append(results, result)
+	}
+
+	return results, total, rows.Err()
+}
+
+// prefixColumns qualifies every column in a comma separated list with alias
+func prefixColumns(alias, columns string) string {
+	parts := strings.Split(columns, ",")
+	for i, part := range parts {
+		parts[i] = alias + "." + strings.TrimSpace(part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// escapeLike escapes the LIKE wildcards in user input
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,407 @@
+package helper
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"shawty-ur/api/utils/db"
+
+	"github.com/lib/pq"
+)
+
+// rollupStateName names the watermark of the click rollups in analytics_rollup_state
+const rollupStateName = "clicks"
+
+// Rollup tables
+const (
+	rollupsHourly = "analytics_rollups_hourly"
+	rollupsDaily  = "analytics_rollups_daily"
+)
+
+// dimensionVariant is the rollup dimension counting clicks per A/B variant id
+const dimensionVariant = "variant"
+
+// breakdownDimensions are the url_analytics columns clicks are rolled up and
+// broken down by, with the value reported for clicks where the column is NULL
+var breakdownDimensions = []struct {
+	column  string
+	missing string
+}{
+	{"device_type", "unknown"},
+	{"browser", "unknown"},
+	{"os", "unknown"},
+	{"referrer_domain", "(none)"},
+	{"referrer_category", "unknown"},
+	{"utm_source", "(none)"},
+	{"utm_medium", "(none)"},
+	{"utm_campaign", "(none)"},
+	{"country", "unknown"},
+}
+
+// clickDimensions expands every url_analytics row (aliased a) into one row
+// per dimension: the link total (empty dimension and value), each breakdown
+// column and the variant
+var clickDimensions = func() string {
+	values := []string{"('', '')"}
+	for _, d := range breakdownDimensions {
+		values = append(values, fmt.Sprintf("('%s', a.%s)", d.column, d.column))
+	}
+	values = append(values, fmt.Sprintf("('%s', a.variant_id::text)", dimensionVariant))
+	return `CROSS JOIN LATERAL (VALUES ` + strings.Join(values, ", ") + `) AS d(dimension, value)`
+}()
+
+// clickAggregates are the counters kept per rollup row
+const clickAggregates = `COUNT(*), COUNT(*) FILTER (WHERE a.is_bot), COUNT(*) FILTER (WHERE a.via_qr)`
+
+// RollupWatermark returns the time up to which clicks are rolled up, or the
+// zero time when nothing is
+func (s *AnalyticsStore) RollupWatermark(ctx context.Context) (time.Time, error) {
+	var watermark sql.NullTime
+	err := s.Db.QueryRowContext(ctx, `SELECT watermark FROM analytics_rollup_state WHERE name = $1`, rollupStateName).Scan(&watermark)
+	if err != nil && err != sql.ErrNoRows {
+		slog.ErrorContext(ctx, "Failed to get rollup watermark", "error", err)
+		return time.Time{}, err
+	}
+	return watermark.Time, nil
+}
+
+// RollupClicks aggregates the clicks after the watermark into the hourly and
+// daily rollups, at most maxHours at a time, and moves the watermark past
+// them. Hours are only rolled up once they are lateness old, so clicks still
+// being written land in the right one.
+//
+// Each run recomputes whole hours and days from their source rows, so it is
+// safe to repeat, and it holds a lock on the watermark so only one replica
+// aggregates at a time. It returns the new watermark and whether it moved.
+func (s *AnalyticsStore) RollupClicks(ctx context.Context, maxHours int, lateness time.Duration) (time.Time, bool, error) {
+	var watermark time.Time
+	advanced := false
+
+	err := db.WithTx(s.Db, ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO analytics_rollup_state(name) VALUES ($1) ON CONFLICT (name) DO NOTHING
+		`, rollupStateName); err != nil {
+			return err
+		}
+
+		var current sql.NullTime
+		err := tx.QueryRowContext(ctx, `
+			SELECT watermark FROM analytics_rollup_state WHERE name = $1 FOR UPDATE SKIP LOCKED
+		`, rollupStateName).Scan(&current)
+		if err == sql.ErrNoRows {
+			// Another replica is aggregating
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		limit := time.Now().Add(-lateness).UTC().Truncate(time.Hour)
+		start := current.Time
+		if !current.Valid {
+			// First run: start from the oldest click there is
+			var oldest sql.NullTime
+			if err := tx.QueryRowContext(ctx, `SELECT MIN(clicked_at) FROM url_analytics`).Scan(&oldest); err != nil {
+				return err
+			}
+			start = limit
+			if oldest.Valid {
+				start = oldest.Time.UTC().Truncate(time.Hour)
+			}
+		}
+		end := start.Add(time.Duration(maxHours) * time.Hour)
+		if end.After(limit) {
+			end = limit
+		}
+		watermark = start
+		if !end.After(start) {
+			if !current.Valid {
+				_, err = tx.ExecContext(ctx, `UPDATE analytics_rollup_state SET watermark = $1, updated_at = NOW() WHERE name = $2`, start, rollupStateName)
+			}
+			return err
+		}
+
+		if err := rollupHours(ctx, tx, start, end); err != nil {
+			return err
+		}
+		if err := rollupDays(ctx, tx, start, end); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE analytics_rollup_state SET watermark = $1, updated_at = NOW() WHERE name = $2
+		`, end, rollupStateName); err != nil {
+			return err
+		}
+		watermark, advanced = end, true
+		return nil
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to roll up clicks", "error", err)
+		return time.Time{}, false, err
+	}
+
+	return watermark, advanced, nil
+}
+
+// rollupHours recomputes the hourly rollups of [start, end) from url_analytics
+func rollupHours(ctx context.Context, tx *sql.Tx, start, end time.Time) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+rollupsHourly+` WHERE bucket >= $1 AND bucket < $2`, start, end); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO ` + rollupsHourly + `(url_id, bucket, dimension, value, clicks, bot_clicks, qr_clicks)
+		SELECT a.url_id, date_trunc('hour', a.clicked_at, 'UTC'), d.dimension, COALESCE(d.value, ''), ` + clickAggregates + `
+		FROM url_analytics a ` + clickDimensions + `
+		WHERE a.clicked_at >= $1 AND a.clicked_at < $2 AND a.url_id IS NOT NULL
+		AND (d.dimension <> '` + dimensionVariant + `' OR d.value IS NOT NULL)
+		GROUP BY 1, 2, 3, 4
+	`
+	_, err := tx.ExecContext(ctx, query, start, end)
+	return err
+}
+
+// rollupDays recomputes the daily rollups of the UTC days overlapping
+// [start, end) from the hourly rollups. Days still in progress are rolled up
+// as far as they go, and completed by later runs.
+func rollupDays(ctx context.Context, tx *sql.Tx, start, end time.Time) error {
+	dayStart := start.Truncate(24 * time.Hour)
+	dayEnd := end.Add(-time.Nanosecond).Truncate(24 * time.Hour).Add(24 * time.Hour)
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+rollupsDaily+` WHERE bucket >= $1 AND bucket < $2`, dayStart, dayEnd); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO ` + rollupsDaily + `(url_id, bucket, dimension, value, clicks, bot_clicks, qr_clicks)
+		SELECT url_id, date_trunc('day', bucket, 'UTC'), dimension, value, SUM(clicks), SUM(bot_clicks), SUM(qr_clicks)
+		FROM ` + rollupsHourly + `
+		WHERE bucket >= $1 AND bucket < $2
+		GROUP BY 1, 2, 3, 4
+	`
+	_, err := tx.ExecContext(ctx, query, dayStart, dayEnd)
+	return err
+}
+
+// PruneClicks deletes up to limit raw click rows from before the hour of the
+// given time, never touching clicks that aren't rolled up yet, and returns
+// how many it deleted. Keeping whole hours means the rollups can always be
+// rebuilt from the remaining clicks.
+func (s *AnalyticsStore) PruneClicks(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM url_analytics WHERE id IN (
+			SELECT id FROM url_analytics
+			WHERE clicked_at < date_trunc('hour', LEAST($1, COALESCE(
+				(SELECT watermark FROM analytics_rollup_state WHERE name = $2), '-infinity')), 'UTC')
+			LIMIT $3
+		)
+	`
+
+	result, err := s.Db.ExecContext(ctx, query, before, rollupStateName, limit)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to prune raw clicks", "error", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PruneHourlyRollups deletes hourly rollups from before the given time
+func (s *AnalyticsStore) PruneHourlyRollups(ctx context.Context, before time.Time) (int64, error) {
+	return s.pruneRollups(ctx, rollupsHourly, before)
+}
+
+// PruneDailyRollups deletes daily rollups from before the given time
+func (s *AnalyticsStore) PruneDailyRollups(ctx context.Context, before time.Time) (int64, error) {
+	return s.pruneRollups(ctx, rollupsDaily, before)
+}
+
+func (s *AnalyticsStore) pruneRollups(ctx context.Context, table string, before time.Time) (int64, error) {
+	result, err := s.Db.ExecContext(ctx, `DELETE FROM `+table+` WHERE bucket < $1`, before)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to prune rollups", "error", err, "table", table)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Stats intervals, the size of the buckets of a time series
+const (
+	IntervalHour = "hour"
+	IntervalDay  = "day"
+)
+
+// statsSegment is a part of a stats range read from one source: raw
+// url_analytics rows ("") or one of the rollup tables
+type statsSegment struct {
+	table    string
+	from, to time.Time
+}
+
+// planSegments splits [from, to) so that whole days before the watermark
+// are read from the daily rollups, the remaining whole hours before it from
+// the hourly rollups, and only the uneven edges and the clicks after the
+// watermark from the raw rows. Without useDaily, whole days are read from
+// the hourly rollups too.
+func planSegments(from, to, watermark time.Time, useDaily bool) []statsSegment {
+	from, to, watermark = from.UTC(), to.UTC(), watermark.UTC()
+
+	hourStart := ceilTime(from, time.Hour)
+	hourEnd := watermark.Truncate(time.Hour)
+	if to.Before(hourEnd) {
+		hourEnd = to.Truncate(time.Hour)
+	}
+	if !hourStart.Before(hourEnd) {
+		return []statsSegment{{"", from, to}}
+	}
+	dayStart := ceilTime(hourStart, 24*time.Hour)
+	dayEnd := hourEnd.Truncate(24 * time.Hour)
+
+	segments := []statsSegment{{"", from, hourStart}}
+	if useDaily && dayStart.Before(dayEnd) {
+		segments = append(segments,
+			statsSegment{rollupsHourly, hourStart, dayStart},
+			statsSegment{rollupsDaily, dayStart, dayEnd},
+			statsSegment{rollupsHourly, dayEnd, hourEnd},
+		)
+	} else {
+		segments = append(segments, statsSegment{rollupsHourly, hourStart, hourEnd})
+	}
+	segments = append(segments, statsSegment{"", hourEnd, to})
+
+	nonEmpty := segments[:0]
+	for _, segment := range segments {
+		if segment.from.Before(segment.to) {
+			nonEmpty = append(nonEmpty, segment)
+		}
+	}
+	return nonEmpty
+}
+
+// ceilTime rounds t up to a multiple of d
+func ceilTime(t time.Time, d time.Duration) time.Time {
+	if truncated := t.Truncate(d); truncated.Before(t) {
+		return truncated.Add(d)
+	}
+	return t
+}
+
+// clickScope picks the links clicks are counted for, as a condition on
+// url_id with its argument as $1
+type clickScope struct {
+	condition string
+	arg       any
+}
+
+func linkScope(urlID int64) clickScope {
+	return clickScope{`url_id = $1`, urlID}
+}
+
+func userScope(userID int64) clickScope {
+	return clickScope{`url_id IN (SELECT id FROM urls WHERE user_id = $1)`, userID}
+}
+
+// teamScope covers the links on a team's branded domains
+func teamScope(teamID int64) clickScope {
+	return clickScope{`url_id IN (SELECT u.id FROM urls u JOIN domains d ON d.id = u.domain_id WHERE d.team_id = $1)`, teamID}
+}
+
+// clickQuery describes how countClicks groups the clicks it counts
+type clickQuery struct {
+	scope      clickScope
+	from, to   time.Time
+	dimensions []string // Only count these dimensions; "" is the totals
+	perLink    bool     // Count per url_id
+	interval   string   // Count per bucket of this size, if set
+}
+
+// dimensionCount is the number of clicks for one dimension value, of a link
+// and bucket if the query asked for them
+type dimensionCount struct {
+	urlID     int64
+	bucket    time.Time
+	dimension string
+	value     string
+	clicks    int64
+	botClicks int64
+	qrClicks  int64
+}
+
+// countClicks counts clicks between from and to per dimension value, reading
+// from the rollups wherever they cover the range
+func (s *AnalyticsStore) countClicks(ctx context.Context, q clickQuery) ([]dimensionCount, error) {
+	watermark, err := s.RollupWatermark(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []any{q.scope.arg, pq.Array(q.dimensions)}
+	var parts []string
+	for _, segment := range planSegments(q.from, q.to, watermark, q.interval != IntervalHour) {
+		args = append(args, segment.from, segment.to)
+		n := len(args)
+		if segment.table == "" {
+			parts = append(parts, fmt.Sprintf(`
+				SELECT a.url_id, date_trunc('hour', a.clicked_at, 'UTC'), d.dimension, COALESCE(d.value, ''), %s
+				FROM url_analytics a %s
+				WHERE a.%s AND a.clicked_at >= $%d AND a.clicked_at < $%d
+				AND d.dimension = ANY($2) AND (d.dimension <> '%s' OR d.value IS NOT NULL)
+				GROUP BY 1, 2, 3, 4`, clickAggregates, clickDimensions, q.scope.condition, n-1, n, dimensionVariant))
+		} else {
+			parts = append(parts, fmt.Sprintf(`
+				SELECT url_id, bucket, dimension, value, clicks, bot_clicks, qr_clicks
+				FROM %s
+				WHERE %s AND bucket >= $%d AND bucket < $%d AND dimension = ANY($2)`, segment.table, q.scope.condition, n-1, n))
+		}
+	}
+
+	keys := []string{"dimension", "value"}
+	if q.perLink {
+		keys = append(keys, "url_id")
+	}
+	if q.interval != "" {
+		// interval is one of the Interval constants
+		keys = append(keys, fmt.Sprintf("date_trunc('%s', bucket, 'UTC')", q.interval))
+	}
+	groupBy := make([]string, len(keys))
+	for i := range keys {
+		groupBy[i] = strconv.Itoa(i + 1)
+	}
+
+	query := `
+		SELECT ` + strings.Join(keys, ", ") + `, SUM(clicks), SUM(bot_clicks), SUM(qr_clicks)
+		FROM (` + strings.Join(parts, "\nUNION ALL") + `
+		) AS counts(url_id, bucket, dimension, value, clicks, bot_clicks, qr_clicks)
+		GROUP BY ` + strings.Join(groupBy, ", ")
+
+	rows, err := s.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to count clicks", "error", err, "scope", q.scope.arg)
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []dimensionCount{}
+	for rows.Next() {
+		var c dimensionCount
+		dest := []any{&c.dimension, &c.value}
+		if q.perLink {
+			dest = append(dest, &c.urlID)
+		}
+		if q.interval != "" {
+			dest = append(dest, &c.bucket)
+		}
+		if err := rows.Scan(append(dest, &c.clicks, &c.botClicks, &c.qrClicks)...); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan click count row", "error", err)
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
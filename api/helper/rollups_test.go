@@ -0,0 +1,140 @@
+package helper
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func at(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCeilTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		d    time.Duration
+		want string
+	}{
+		{"2026-01-05T10:00:00Z", time.Hour, "2026-01-05T10:00:00Z"},
+		{"2026-01-05T10:00:01Z", time.Hour, "2026-01-05T11:00:00Z"},
+		{"2026-01-05T10:59:59Z", time.Hour, "2026-01-05T11:00:00Z"},
+		{"2026-01-05T00:00:00Z", 24 * time.Hour, "2026-01-05T00:00:00Z"},
+		{"2026-01-05T00:30:00Z", 24 * time.Hour, "2026-01-06T00:00:00Z"},
+		{"2026-12-31T23:00:00Z", 24 * time.Hour, "2027-01-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		if got := ceilTime(at(tt.in), tt.d); !got.Equal(at(tt.want)) {
+			t.Errorf("ceilTime(%s, %s) = %s, want %s", tt.in, tt.d, got.Format(time.RFC3339), tt.want)
+		}
+	}
+}
+
+func TestPlanSegments(t *testing.T) {
+	tests := []struct {
+		name      string
+		from, to  string
+		watermark string
+		useDaily  bool
+		want      []statsSegment
+	}{
+		{
+			name:      "nothing rolled up yet",
+			from:      "2026-01-05T10:15:00Z",
+			to:        "2026-01-05T12:45:00Z",
+			watermark: "2026-01-05T10:00:00Z",
+			useDaily:  true,
+			want:      []statsSegment{{"", at("2026-01-05T10:15:00Z"), at("2026-01-05T12:45:00Z")}},
+		},
+		{
+			name:      "range within one hour",
+			from:      "2026-01-05T10:15:00Z",
+			to:        "2026-01-05T10:45:00Z",
+			watermark: "2026-01-06T00:00:00Z",
+			useDaily:  true,
+			want:      []statsSegment{{"", at("2026-01-05T10:15:00Z"), at("2026-01-05T10:45:00Z")}},
+		},
+		{
+			name:      "hours with uneven edges",
+			from:      "2026-01-05T10:15:00Z",
+			to:        "2026-01-05T14:45:00Z",
+			watermark: "2026-01-06T00:00:00Z",
+			useDaily:  true,
+			want: []statsSegment{
+				{"", at("2026-01-05T10:15:00Z"), at("2026-01-05T11:00:00Z")},
+				{rollupsHourly, at("2026-01-05T11:00:00Z"), at("2026-01-05T14:00:00Z")},
+				{"", at("2026-01-05T14:00:00Z"), at("2026-01-05T14:45:00Z")},
+			},
+		},
+		{
+			name:      "whole hours",
+			from:      "2026-01-05T10:00:00Z",
+			to:        "2026-01-05T14:00:00Z",
+			watermark: "2026-01-06T00:00:00Z",
+			useDaily:  true,
+			want:      []statsSegment{{rollupsHourly, at("2026-01-05T10:00:00Z"), at("2026-01-05T14:00:00Z")}},
+		},
+		{
+			name:      "clicks after the watermark are raw",
+			from:      "2026-01-05T10:00:00Z",
+			to:        "2026-01-05T14:00:00Z",
+			watermark: "2026-01-05T12:30:00Z",
+			useDaily:  true,
+			want: []statsSegment{
+				{rollupsHourly, at("2026-01-05T10:00:00Z"), at("2026-01-05T12:00:00Z")},
+				{"", at("2026-01-05T12:00:00Z"), at("2026-01-05T14:00:00Z")},
+			},
+		},
+		{
+			name:      "days in the middle",
+			from:      "2026-01-03T22:30:00Z",
+			to:        "2026-01-07T03:10:00Z",
+			watermark: "2026-01-08T00:00:00Z",
+			useDaily:  true,
+			want: []statsSegment{
+				{"", at("2026-01-03T22:30:00Z"), at("2026-01-03T23:00:00Z")},
+				{rollupsHourly, at("2026-01-03T23:00:00Z"), at("2026-01-04T00:00:00Z")},
+				{rollupsDaily, at("2026-01-04T00:00:00Z"), at("2026-01-07T00:00:00Z")},
+				{rollupsHourly, at("2026-01-07T00:00:00Z"), at("2026-01-07T03:00:00Z")},
+				{"", at("2026-01-07T03:00:00Z"), at("2026-01-07T03:10:00Z")},
+			},
+		},
+		{
+			name:      "days from hourly rollups without useDaily",
+			from:      "2026-01-03T22:30:00Z",
+			to:        "2026-01-07T03:10:00Z",
+			watermark: "2026-01-08T00:00:00Z",
+			useDaily:  false,
+			want: []statsSegment{
+				{"", at("2026-01-03T22:30:00Z"), at("2026-01-03T23:00:00Z")},
+				{rollupsHourly, at("2026-01-03T23:00:00Z"), at("2026-01-07T03:00:00Z")},
+				{"", at("2026-01-07T03:00:00Z"), at("2026-01-07T03:10:00Z")},
+			},
+		},
+		{
+			name:      "times in other zones",
+			from:      "2026-01-05T12:15:00+02:00",
+			to:        "2026-01-05T13:00:00+02:00",
+			watermark: "2026-01-06T00:00:00Z",
+			useDaily:  true,
+			want: []statsSegment{
+				{"", at("2026-01-05T10:15:00Z"), at("2026-01-05T11:00:00Z")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := planSegments(at(tt.from), at(tt.to), at(tt.watermark), tt.useDaily)
+			equal := slices.EqualFunc(got, tt.want, func(a, b statsSegment) bool {
+				return a.table == b.table && a.from.Equal(b.from) && a.to.Equal(b.to)
+			})
+			if !equal {
+				t.Errorf("planSegments() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
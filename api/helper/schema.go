@@ -0,0 +1,46 @@
+package helper
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+)
+
+// SchemaStore reads the state of the database schema
+type SchemaStore struct {
+	Db *sql.DB
+}
+
+// NewSchemaStore creates a new schema store
+func NewSchemaStore(db *sql.DB) *SchemaStore {
+	return &SchemaStore{Db: db}
+}
+
+// Version returns the migration version goose last applied, or 0 before the
+// first migration. Like goose, it skips versions that were rolled back.
+func (s *SchemaStore) Version(ctx context.Context) (int64, error) {
+	rows, err := s.Db.QueryContext(ctx, `SELECT version_id, is_applied FROM goose_db_version ORDER BY id DESC`)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to read schema version", "error", err)
+		return 0, err
+	}
+	defer rows.Close()
+
+	rolledBack := map[int64]bool{}
+	for rows.Next() {
+		var version int64
+		var applied bool
+		if err := rows.Scan(&version, &applied); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan schema version row", "error", err)
+			return 0, err
+		}
+		if rolledBack[version] {
+			continue
+		}
+		if applied {
+			return version, rows.Err()
+		}
+		rolledBack[version] = true
+	}
+	return 0, rows.Err()
+}
@@ -0,0 +1,101 @@
+package helper
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"shawty-ur/api/models"
+	"shawty-ur/api/utils/db"
+)
+
+// TeamStore handles all database operations for teams and their members
+type TeamStore struct {
+	Db *sql.DB
+}
+
+// NewTeamStore creates a new team store
+func NewTeamStore(db *sql.DB) *TeamStore {
+	return &TeamStore{Db: db}
+}
+
+// CreateTeam creates a team with ownerID as its owner
+func (s *TeamStore) CreateTeam(ctx context.Context, team *models.Team, ownerID int64) error {
+	return db.WithTx(s.Db, ctx, func(tx *sql.Tx) error {
+		query := `INSERT INTO teams(name) VALUES ($1) RETURNING id, created_at, updated_at`
+		if err := tx.QueryRowContext(ctx, query, team.Name).Scan(&team.ID, &team.CreatedAt, &team.UpdatedAt); err != nil {
+			slog.ErrorContext(ctx, "Failed to create team", "error", err, "name", team.Name)
+			return err
+		}
+
+		query = `INSERT INTO team_members(team_id, user_id, role) VALUES ($1, $2, $3)`
+		if _, err := tx.ExecContext(ctx, query, team.ID, ownerID, models.TeamRoleOwner); err != nil {
+			slog.ErrorContext(ctx, "Failed to add team owner", "error", err, "team_id", team.ID)
+			return err
+		}
+
+		team.Role = models.TeamRoleOwner
+		slog.InfoContext(ctx, "Team created", "id", team.ID, "owner_id", ownerID)
+		return nil
+	})
+}
+
+// ListTeamsForUser retrieves the teams a user belongs to, with their role
+func (s *TeamStore) ListTeamsForUser(ctx context.Context, userID int64) ([]*models.Team, error) {
+	query := `
+		SELECT t.id, t.name, m.role, t.created_at, t.updated_at
+		FROM teams t
+		JOIN team_members m ON m.team_id = t.id
+		WHERE m.user_id = $1
+		ORDER BY t.name
+	`
+
+	rows, err := s.Db.QueryContext(ctx, query, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list teams", "error", err, "user_id", userID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := []*models.Team{}
+	for rows.Next() {
+		team := &models.Team{}
+		if err := rows.Scan(&team.ID, &team.Name, &team.Role, &team.CreatedAt, &team.UpdatedAt); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan team row", "error", err)
+			return nil, err
+		}
+		teams = append(teams, team)
+	}
+
+	return teams, rows.Err()
+}
+
+// GetMemberRole returns the user's role in a team, or "" if they are not a member
+func (s *TeamStore) GetMemberRole(ctx context.Context, teamID, userID int64) (string, error) {
+	query := `SELECT role FROM team_members WHERE team_id = $1 AND user_id = $2`
+
+	var role string
+	err := s.Db.QueryRowContext(ctx, query, teamID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get team member role", "error", err, "team_id", teamID, "user_id", userID)
+		return "", err
+	}
+	return role, nil
+}
+
+// AddMember adds a user to a team, or updates their role if already a member
+func (s *TeamStore) AddMember(ctx context.Context, teamID, userID int64, role string) error {
+	query := `
+		INSERT INTO team_members(team_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (team_id, user_id) DO UPDATE SET role = EXCLUDED.role
+	`
+
+	if _, err := s.Db.ExecContext(ctx, query, teamID, userID, role); err != nil {
+		slog.ErrorContext(ctx, "Failed to add team member", "error", err, "team_id", teamID, "user_id", userID)
+		return err
+	}
+	return nil
+}
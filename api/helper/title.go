@@ -0,0 +1,57 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"shawty-ur/api/safehttp"
+)
+
+// maxTitleBytes caps how much of the destination page is read looking for <title>
+const maxTitleBytes = 64 * 1024
+
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// titleClient only fetches pages on public addresses, since any visitor can
+// have a title fetched and then read it back
+var titleClient = safehttp.NewClient(10 * time.Second)
+
+// FetchPageTitle downloads the start of an HTML page and returns its <title>
+func FetchPageTitle(ctx context.Context, rawURL string) (string, error) {
+	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		rawURL = "https://" + rawURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := titleClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("page returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTitleBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read page: %w", err)
+	}
+
+	match := titlePattern.FindSubmatch(body)
+	if match == nil {
+		return "", nil
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(string(match[1]))), " "), nil
+}
@@ -19,7 +19,7 @@ func NewUserStore(db *sql.DB) *UserStore {
 }
 
 func (s *UserStore) CreateUser(ctx context.Context, tx *sql.Tx, user *models.User) error {
-	slog.Info("Creating User Query: ", "email: ", user.Email)
+	slog.InfoContext(ctx, "Creating User Query: ", "email: ", user.Email)
 	query := `INSERT INTO users(email, password_hash, username, provider)
 			VALUES ($1, $2, $3, 'local') ON CONFLICT(email) DO NOTHING
 			RETURNING id, created_at, updated_at`
@@ -32,7 +32,7 @@ func (s *UserStore) CreateUser(ctx context.Context, tx *sql.Tx, user *models.Use
 	if err == sql.ErrNoRows {
 		//user already exists
 		// return errors.New("duplicate_user")
-		slog.Error("Error creating user !!!", "err: ", err)
+		slog.ErrorContext(ctx, "Error creating user !!!", "err: ", err)
 		return nil
 	}
 	if err != nil {
@@ -59,12 +59,12 @@ func (s *UserStore) GetUserByUsernameOrEmail(ctx context.Context, tx *sql.Tx, us
 	)
 
 	if err == sql.ErrNoRows {
-		slog.Warn("User not found", "username", user.Username, "email", user.Email)
+		slog.WarnContext(ctx, "User not found", "username", user.Username, "email", user.Email)
 		return nil
 	}
 
 	if err != nil {
-		slog.Error("Failed to get user by username or email", "error", err)
+		slog.ErrorContext(ctx, "Failed to get user by username or email", "error", err)
 		return err
 	}
 
@@ -90,12 +90,12 @@ func (s *UserStore) GetUserByEmail(ctx context.Context, email string) (*models.U
 	)
 
 	if err == sql.ErrNoRows {
-		slog.Warn("User not found", "email", email)
+		slog.WarnContext(ctx, "User not found", "email", email)
 		return nil, nil
 	}
 
 	if err != nil {
-		slog.Error("Failed to get user by email", "error", err)
+		slog.ErrorContext(ctx, "Failed to get user by email", "error", err)
 		return nil, err
 	}
 
@@ -112,7 +112,7 @@ func (s *UserStore) ListUsers(ctx context.Context) ([]*models.User, error) {
 
 	rows, err := s.Db.QueryContext(ctx, query)
 	if err != nil {
-		slog.Error("Failed to list users", "error", err)
+		slog.ErrorContext(ctx, "Failed to list users", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -129,7 +129,7 @@ func (s *UserStore) ListUsers(ctx context.Context) ([]*models.User, error) {
 			&user.Provider,
 		)
 		if err != nil {
-			slog.Error("Failed to scan user row", "error", err)
+			slog.ErrorContext(ctx, "Failed to scan user row", "error", err)
 			return nil, err
 		}
 		users = append(users, user)
@@ -144,7 +144,7 @@ func (s *UserStore) DeleteUser(ctx context.Context, id int64) error {
 
 	result, err := s.Db.ExecContext(ctx, query, id)
 	if err != nil {
-		slog.Error("Failed to delete user", "error", err, "id", id)
+		slog.ErrorContext(ctx, "Failed to delete user", "error", err, "id", id)
 		return err
 	}
 
@@ -154,10 +154,10 @@ func (s *UserStore) DeleteUser(ctx context.Context, id int64) error {
 	}
 
 	if rowsAffected == 0 {
-		slog.Warn("User not found for deletion", "id", id)
+		slog.WarnContext(ctx, "User not found for deletion", "id", id)
 	}
 
-	slog.Info("User deleted", "id", id)
+	slog.InfoContext(ctx, "User deleted", "id", id)
 	return nil
 }
 
@@ -179,11 +179,11 @@ func (s *UserStore) UpdateUser(ctx context.Context, user *models.User) error {
 	).Scan(&user.UpdatedAt)
 
 	if err != nil {
-		slog.Error("Failed to update user", "error", err, "id", user.ID)
+		slog.ErrorContext(ctx, "Failed to update user", "error", err, "id", user.ID)
 		return err
 	}
 
-	slog.Info("User updated", "id", user.ID)
+	slog.InfoContext(ctx, "User updated", "id", user.ID)
 	return nil
 }
 
@@ -213,7 +213,7 @@ func (s *UserStore) GetUserByProviderID(ctx context.Context, provider, providerI
 	}
 
 	if err != nil {
-		slog.Error("Failed to get user by provider ID", "error", err, "provider", provider)
+		slog.ErrorContext(ctx, "Failed to get user by provider ID", "error", err, "provider", provider)
 		return nil, err
 	}
 
@@ -240,11 +240,11 @@ func (s *UserStore) CreateOAuthUser(ctx context.Context, user *models.User) erro
 	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
-		slog.Error("Failed to create OAuth user", "error", err, "email", user.Email)
+		slog.ErrorContext(ctx, "Failed to create OAuth user", "error", err, "email", user.Email)
 		return err
 	}
 
-	slog.Info("OAuth user created successfully", "id", user.ID, "email", user.Email, "provider", user.Provider)
+	slog.InfoContext(ctx, "OAuth user created successfully", "id", user.ID, "email", user.Email, "provider", user.Provider)
 	return nil
 }
 
@@ -258,7 +258,7 @@ func (s *UserStore) FindOrCreateOAuthUser(ctx context.Context, googleUser *model
 
 	// If user exists, return it
 	if user != nil {
-		slog.Info("Found existing OAuth user", "id", user.ID, "email", user.Email)
+		slog.InfoContext(ctx, "Found existing OAuth user", "id", user.ID, "email", user.Email)
 		return user, nil
 	}
 
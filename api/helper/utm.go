@@ -0,0 +1,172 @@
+package helper
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"shawty-ur/api/models"
+)
+
+// ApplyUTM merges UTM parameters into the query string of rawURL.
+// Existing parameters keep their position and encoding, UTM keys that are
+// being set replace any previous value, and the fragment is left untouched.
+// URLs without a scheme (accepted by shorten) are returned without one.
+func ApplyUTM(rawURL string, utm models.UTMParams) (string, error) {
+	values := utm.Values()
+	if len(values) == 0 {
+		return rawURL, nil
+	}
+	if err := utm.Validate(); err != nil {
+		return "", err
+	}
+
+	u, format, err := parseDestination(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	replaced := make(map[string]bool, len(values))
+	for _, kv := range values {
+		replaced[kv[0]] = true
+	}
+
+	var params []string
+	for _, param := range splitQuery(u.RawQuery) {
+		if replaced[queryKey(param)] {
+			continue
+		}
+		params = append(params, param)
+	}
+	for _, kv := range values {
+		params = append(params, kv[0]+"="+url.QueryEscape(kv[1]))
+	}
+	u.RawQuery = strings.Join(params, "&")
+
+	return format(u), nil
+}
+
+// UTMPresetStore handles all database operations for UTM presets
+type UTMPresetStore struct {
+	Db *sql.DB
+}
+
+// NewUTMPresetStore creates a new UTM preset store
+func NewUTMPresetStore(db *sql.DB) *UTMPresetStore {
+	return &UTMPresetStore{Db: db}
+}
+
+const utmPresetColumns = `id, user_id, name,
+	COALESCE(utm_source, ''), COALESCE(utm_medium, ''), COALESCE(utm_campaign, ''),
+	COALESCE(utm_term, ''), COALESCE(utm_content, ''), created_at, updated_at`
+
+func scanUTMPreset(row rowScanner, preset *models.UTMPreset) error {
+	return row.Scan(
+		&preset.ID,
+		&preset.UserID,
+		&preset.Name,
+		&preset.UTM.Source,
+		&preset.UTM.Medium,
+		&preset.UTM.Campaign,
+		&preset.UTM.Term,
+		&preset.UTM.Content,
+		&preset.CreatedAt,
+		&preset.UpdatedAt,
+	)
+}
+
+// SavePreset creates a preset or replaces the one with the same name
+func (s *UTMPresetStore) SavePreset(ctx context.Context, preset *models.UTMPreset) error {
+	query := `
+		INSERT INTO utm_presets(user_id, name, utm_source, utm_medium, utm_campaign, utm_term, utm_content)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''))
+		ON CONFLICT (user_id, name) DO UPDATE SET
+			utm_source = EXCLUDED.utm_source,
+			utm_medium = EXCLUDED.utm_medium,
+			utm_campaign = EXCLUDED.utm_campaign,
+			utm_term = EXCLUDED.utm_term,
+			utm_content = EXCLUDED.utm_content,
+			updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`
+
+	err := s.Db.QueryRowContext(
+		ctx,
+		query,
+		preset.UserID,
+		preset.Name,
+		preset.UTM.Source,
+		preset.UTM.Medium,
+		preset.UTM.Campaign,
+		preset.UTM.Term,
+		preset.UTM.Content,
+	).Scan(&preset.ID, &preset.CreatedAt, &preset.UpdatedAt)
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to save UTM preset", "error", err, "user_id", preset.UserID, "name", preset.Name)
+		return err
+	}
+	return nil
+}
+
+// GetPresetByName retrieves one of a user's presets
+func (s *UTMPresetStore) GetPresetByName(ctx context.Context, userID int64, name string) (*models.UTMPreset, error) {
+	query := `SELECT ` + utmPresetColumns + ` FROM utm_presets WHERE user_id = $1 AND name = $2`
+
+	preset := &models.UTMPreset{}
+	err := scanUTMPreset(s.Db.QueryRowContext(ctx, query, userID, name), preset)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get UTM preset", "error", err, "user_id", userID, "name", name)
+		return nil, err
+	}
+
+	return preset, nil
+}
+
+// ListPresets retrieves all presets of a user
+func (s *UTMPresetStore) ListPresets(ctx context.Context, userID int64) ([]*models.UTMPreset, error) {
+	query := `SELECT ` + utmPresetColumns + ` FROM utm_presets WHERE user_id = $1 ORDER BY name`
+
+	rows, err := s.Db.QueryContext(ctx, query, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list UTM presets", "error", err, "user_id", userID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	presets := []*models.UTMPreset{}
+	for rows.Next() {
+		preset := &models.UTMPreset{}
+		if err := scanUTMPreset(rows, preset); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan UTM preset row", "error", err)
+			return nil, err
+		}
+		presets = append(presets, preset)
+	}
+
+	return presets, rows.Err()
+}
+
+// DeletePreset removes one of a user's presets, reporting whether it existed
+func (s *UTMPresetStore) DeletePreset(ctx context.Context, userID int64, name string) (bool, error) {
+	query := `DELETE FROM utm_presets WHERE user_id = $1 AND name = $2`
+
+	result, err := s.Db.ExecContext(ctx, query, userID, name)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to delete UTM preset", "error", err, "user_id", userID, "name", name)
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
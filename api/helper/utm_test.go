@@ -0,0 +1,46 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"shawty-ur/api/models"
+)
+
+func TestApplyUTM(t *testing.T) {
+	newsletter := models.UTMParams{Source: "newsletter", Medium: "email", Campaign: "spring"}
+
+	tests := []struct {
+		name    string
+		rawURL  string
+		utm     models.UTMParams
+		want    string
+		wantErr bool
+	}{
+		{"no params", "https://example.com/a?b=1", models.UTMParams{}, "https://example.com/a?b=1", false},
+		{"adds params", "https://example.com/sale", models.UTMParams{Source: "newsletter"}, "https://example.com/sale?utm_source=newsletter", false},
+		{"keeps existing params", "https://example.com/sale?ref=1&b=%2F", models.UTMParams{Source: "newsletter"}, "https://example.com/sale?ref=1&b=%2F&utm_source=newsletter", false},
+		{"overrides existing utm", "https://example.com/?utm_source=old&ref=1&utm_medium=web", models.UTMParams{Source: "new"}, "https://example.com/?ref=1&utm_medium=web&utm_source=new", false},
+		{"overrides escaped key", "https://example.com/?utm%5Fsource=old", models.UTMParams{Source: "new"}, "https://example.com/?utm_source=new", false},
+		{"canonical order", "https://example.com/", models.UTMParams{Content: "hero", Source: "ads"}, "https://example.com/?utm_source=ads&utm_content=hero", false},
+		{"escapes values", "https://example.com/", models.UTMParams{Campaign: "spring sale&more=1"}, "https://example.com/?utm_campaign=spring+sale%26more%3D1", false},
+		{"keeps fragment", "https://example.com/sale?ref=1#top", models.UTMParams{Source: "x"}, "https://example.com/sale?ref=1&utm_source=x#top", false},
+		{"no scheme", "example.com/sale", models.UTMParams{Source: "x"}, "example.com/sale?utm_source=x", false},
+		{"preset", "https://example.com/", models.UTMParams{}.Merge(newsletter), "https://example.com/?utm_source=newsletter&utm_medium=email&utm_campaign=spring", false},
+		{"fields win over preset", "https://example.com/", models.UTMParams{Campaign: "summer", Content: "hero"}.Merge(newsletter), "https://example.com/?utm_source=newsletter&utm_medium=email&utm_campaign=summer&utm_content=hero", false},
+		{"longest value", "https://example.com/", models.UTMParams{Term: strings.Repeat("é", models.MaxUTMValueLength)}, "https://example.com/?utm_term=" + strings.Repeat("%C3%A9", models.MaxUTMValueLength), false},
+		{"value too long", "https://example.com/", models.UTMParams{Term: strings.Repeat("a", models.MaxUTMValueLength+1)}, "", true},
+		{"invalid url", "https://exa mple.com/%zz", models.UTMParams{Source: "x"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyUTM(tt.rawURL, tt.utm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyUTM() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ApplyUTM() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package helper
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"math/rand/v2"
+
+	"shawty-ur/api/models"
+	"shawty-ur/api/utils/db"
+
+	"github.com/lib/pq"
+)
+
+// VariantStore handles all database operations for A/B link variants
+type VariantStore struct {
+	Db *sql.DB
+}
+
+// NewVariantStore creates a new variant store
+func NewVariantStore(db *sql.DB) *VariantStore {
+	return &VariantStore{Db: db}
+}
+
+const variantColumns = `id, url_id, name, destination, weight, created_at, updated_at`
+
+func scanVariant(row rowScanner, variant *models.LinkVariant, extra ...any) error {
+	dest := []any{
+		&variant.ID,
+		&variant.URLID,
+		&variant.Name,
+		&variant.Destination,
+		&variant.Weight,
+		&variant.CreatedAt,
+		&variant.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// ListVariants retrieves the variants of a link
+func (s *VariantStore) ListVariants(ctx context.Context, urlID int64) ([]*models.LinkVariant, error) {
+	query := `SELECT ` + variantColumns + ` FROM link_variants WHERE url_id = $1 ORDER BY name`
+
+	rows, err := s.Db.QueryContext(ctx, query, urlID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list link variants", "error", err, "url_id", urlID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := []*models.LinkVariant{}
+	for rows.Next() {
+		variant := &models.LinkVariant{}
+		if err := scanVariant(rows, variant); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan link variant row", "error", err)
+			return nil, err
+		}
+		variants = append(variants, variant)
+	}
+
+	return variants, rows.Err()
+}
+
+// ReplaceVariants makes variants the complete set of variants of a link.
+// Variants are matched by name, so editing a weight or destination keeps the
+// variant's ID (and the sticky assignments and click history tied to it).
+func (s *VariantStore) ReplaceVariants(ctx context.Context, urlID int64, variants []*models.LinkVariant) error {
+	return db.WithTx(s.Db, ctx, func(tx *sql.Tx) error {
+		names := make([]string, 0, len(variants))
+		for _, variant := range variants {
+			names = append(names, variant.Name)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM link_variants WHERE url_id = $1 AND NOT (name = ANY($2))`,
+			urlID, pq.Array(names),
+		); err != nil {
+			slog.ErrorContext(ctx, "Failed to delete removed link variants", "error", err, "url_id", urlID)
+			return err
+		}
+
+		query := `
+			INSERT INTO link_variants(url_id, name, destination, weight)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (url_id, name) DO UPDATE SET
+				destination = EXCLUDED.destination,
+				weight = EXCLUDED.weight,
+				updated_at = NOW()
+			RETURNING ` + variantColumns
+
+		for _, variant := range variants {
+			row := tx.QueryRowContext(ctx, query, urlID, variant.Name, variant.Destination, variant.Weight)
+			if err := scanVariant(row, variant); err != nil {
+				slog.ErrorContext(ctx, "Failed to save link variant", "error", err, "url_id", urlID, "name", variant.Name)
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE urls SET has_variants = $2 WHERE id = $1`, urlID, len(variants) > 0); err != nil {
+			slog.ErrorContext(ctx, "Failed to flag link variants", "error", err, "url_id", urlID)
+			return err
+		}
+		return nil
+	})
+}
+
+// PickVariant chooses a variant at random, proportionally to the weights.
+// It returns nil when there is nothing to pick (no variants or all weights zero).
+func PickVariant(variants []*models.LinkVariant) *models.LinkVariant {
+	total := 0
+	for _, variant := range variants {
+		total += variant.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	n := rand.IntN(total)
+	for _, variant := range variants {
+		if n < variant.Weight {
+			return variant
+		}
+		n -= variant.Weight
+	}
+	return nil
+}
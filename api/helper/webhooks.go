@@ -0,0 +1,316 @@
+package helper
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"shawty-ur/api/models"
+
+	"github.com/lib/pq"
+)
+
+// webhookSecretPrefix marks webhook signing secrets so they're recognisable
+const webhookSecretPrefix = "whsec_"
+
+// WebhookStore handles all database operations for webhook endpoints and
+// their delivery outbox
+type WebhookStore struct {
+	Db *sql.DB
+}
+
+// NewWebhookStore creates a new webhook store
+func NewWebhookStore(db *sql.DB) *WebhookStore {
+	return &WebhookStore{Db: db}
+}
+
+// PendingDelivery is a claimed delivery along with where and how to send it
+type PendingDelivery struct {
+	models.WebhookDelivery
+	URL    string
+	Secret string
+}
+
+const endpointColumns = `id, user_id, url, secret, events, click_sample_rate, active, created_at, updated_at`
+
+func scanEndpoint(row rowScanner, endpoint *models.WebhookEndpoint) error {
+	return row.Scan(
+		&endpoint.ID,
+		&endpoint.UserID,
+		&endpoint.URL,
+		&endpoint.Secret,
+		pq.Array(&endpoint.Events),
+		&endpoint.ClickSampleRate,
+		&endpoint.Active,
+		&endpoint.CreatedAt,
+		&endpoint.UpdatedAt,
+	)
+}
+
+const deliveryColumns = `id, endpoint_id, event, payload, status, attempts, next_attempt_at,
+	last_status_code, last_error, delivered_at, created_at`
+
+func scanDelivery(row rowScanner, delivery *models.WebhookDelivery, extra ...any) error {
+	dest := []any{
+		&delivery.ID,
+		&delivery.EndpointID,
+		&delivery.Event,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastStatusCode,
+		&delivery.LastError,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// CreateEndpoint registers a webhook endpoint with a freshly generated signing secret
+func (s *WebhookStore) CreateEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	endpoint.Secret = webhookSecretPrefix + hex.EncodeToString(secret)
+	endpoint.Active = true
+
+	query := `
+		INSERT INTO webhook_endpoints(user_id, url, secret, events, click_sample_rate)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := s.Db.QueryRowContext(ctx, query,
+		endpoint.UserID,
+		endpoint.URL,
+		endpoint.Secret,
+		pq.Array(endpoint.Events),
+		endpoint.ClickSampleRate,
+	).Scan(&endpoint.ID, &endpoint.CreatedAt, &endpoint.UpdatedAt)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create webhook endpoint", "error", err, "user_id", endpoint.UserID)
+		return err
+	}
+
+	slog.InfoContext(ctx, "Webhook endpoint created", "id", endpoint.ID, "user_id", endpoint.UserID)
+	return nil
+}
+
+// ListEndpoints retrieves the webhook endpoints of a user
+func (s *WebhookStore) ListEndpoints(ctx context.Context, userID int64) ([]*models.WebhookEndpoint, error) {
+	query := `SELECT ` + endpointColumns + ` FROM webhook_endpoints WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := s.Db.QueryContext(ctx, query, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list webhook endpoints", "error", err, "user_id", userID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []*models.WebhookEndpoint{}
+	for rows.Next() {
+		endpoint := &models.WebhookEndpoint{}
+		if err := scanEndpoint(rows, endpoint); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan webhook endpoint row", "error", err)
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints, rows.Err()
+}
+
+// GetEndpoint retrieves one of a user's webhook endpoints
+func (s *WebhookStore) GetEndpoint(ctx context.Context, userID, id int64) (*models.WebhookEndpoint, error) {
+	query := `SELECT ` + endpointColumns + ` FROM webhook_endpoints WHERE id = $1 AND user_id = $2`
+
+	endpoint := &models.WebhookEndpoint{}
+	err := scanEndpoint(s.Db.QueryRowContext(ctx, query, id, userID), endpoint)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get webhook endpoint", "error", err, "id", id)
+		return nil, err
+	}
+
+	return endpoint, nil
+}
+
+// DeleteEndpoint removes one of a user's webhook endpoints and its delivery log
+func (s *WebhookStore) DeleteEndpoint(ctx context.Context, userID, id int64) error {
+	if _, err := s.Db.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = $1 AND user_id = $2`, id, userID); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete webhook endpoint", "error", err, "id", id)
+		return err
+	}
+	return nil
+}
+
+// HasActiveEndpoint reports whether userID has an active endpoint subscribed to event
+func (s *WebhookStore) HasActiveEndpoint(ctx context.Context, userID int64, event string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM webhook_endpoints WHERE user_id = $1 AND active AND $2 = ANY(events))`
+
+	var exists bool
+	if err := s.Db.QueryRowContext(ctx, query, userID, event).Scan(&exists); err != nil {
+		slog.ErrorContext(ctx, "Failed to look up webhook endpoints", "error", err, "user_id", userID, "event", event)
+		return false, err
+	}
+	return exists, nil
+}
+
+// Enqueue adds an event to the outbox of every active endpoint of userID
+// subscribed to it. link.clicked is only queued for a sample of clicks, as
+// set per endpoint.
+func (s *WebhookStore) Enqueue(ctx context.Context, userID int64, event string, payload []byte) error {
+	query := `
+		INSERT INTO webhook_deliveries(endpoint_id, event, payload)
+		SELECT id, $2, $3::jsonb FROM webhook_endpoints
+		WHERE user_id = $1 AND active AND $2 = ANY(events)
+		AND ($2 <> $4 OR random() < click_sample_rate)
+	`
+
+	if _, err := s.Db.ExecContext(ctx, query, userID, event, payload, models.EventLinkClicked); err != nil {
+		slog.ErrorContext(ctx, "Failed to enqueue webhook event", "error", err, "user_id", userID, "event", event)
+		return err
+	}
+	return nil
+}
+
+// ClaimDeliveries picks up to limit pending deliveries that are due, counts
+// the attempt and moves their next attempt past the lease, so other replicas
+// skip them while they're being sent. Deliveries of inactive endpoints stay
+// pending until the endpoint is active again.
+func (s *WebhookStore) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*PendingDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries d SET
+			attempts = d.attempts + 1,
+			next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM webhook_endpoints e
+		WHERE e.id = d.endpoint_id AND e.active
+		AND d.id IN (
+			SELECT pd.id FROM webhook_deliveries pd
+			JOIN webhook_endpoints pe ON pe.id = pd.endpoint_id
+			WHERE pd.status = 'pending' AND pd.next_attempt_at <= NOW() AND pe.active
+			ORDER BY pd.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF pd SKIP LOCKED
+		)
+		RETURNING ` + prefixColumns("d", deliveryColumns) + `, e.url, e.secret`
+
+	rows, err := s.Db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to claim webhook deliveries", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*PendingDelivery{}
+	for rows.Next() {
+		delivery := &PendingDelivery{}
+		if err := scanDelivery(rows, &delivery.WebhookDelivery, &delivery.URL, &delivery.Secret); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan webhook delivery row", "error", err)
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// MarkDelivered records a successful delivery
+func (s *WebhookStore) MarkDelivered(ctx context.Context, id int64, statusCode int) error {
+	query := `
+		UPDATE webhook_deliveries SET
+			status = 'delivered', last_status_code = $1, last_error = NULL, delivered_at = NOW()
+		WHERE id = $2
+	`
+
+	if _, err := s.Db.ExecContext(ctx, query, statusCode, id); err != nil {
+		slog.ErrorContext(ctx, "Failed to mark webhook delivered", "error", err, "id", id)
+		return err
+	}
+	return nil
+}
+
+// MarkAttemptFailed records a failed attempt. The delivery is retried at
+// nextAttempt, or given up on when nextAttempt is nil.
+func (s *WebhookStore) MarkAttemptFailed(ctx context.Context, id int64, statusCode *int, errMsg string, nextAttempt *time.Time) error {
+	query := `
+		UPDATE webhook_deliveries SET
+			status = CASE WHEN $3::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+			next_attempt_at = COALESCE($3, next_attempt_at),
+			last_status_code = $1,
+			last_error = $2
+		WHERE id = $4
+	`
+
+	if _, err := s.Db.ExecContext(ctx, query, statusCode, errMsg, nextAttempt, id); err != nil {
+		slog.ErrorContext(ctx, "Failed to record webhook attempt", "error", err, "id", id)
+		return err
+	}
+	return nil
+}
+
+// ListDeliveries retrieves the delivery log of an endpoint, newest first
+func (s *WebhookStore) ListDeliveries(ctx context.Context, endpointID int64, limit, offset int) ([]*models.WebhookDelivery, int, error) {
+	query := `
+		SELECT ` + deliveryColumns + `, COUNT(*) OVER() AS total
+		FROM webhook_deliveries
+		WHERE endpoint_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := s.Db.QueryContext(ctx, query, endpointID, limit, offset)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list webhook deliveries", "error", err, "endpoint_id", endpointID)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	total := 0
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery := &models.WebhookDelivery{}
+		if err := scanDelivery(rows, delivery, &total); err != nil {
+			slog.ErrorContext(ctx, "Failed to scan webhook delivery row", "error", err)
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, total, rows.Err()
+}
+
+// Redeliver queues a past delivery of an endpoint to be sent again right
+// away, with a fresh set of retries. It returns nil when there is no such
+// delivery.
+func (s *WebhookStore) Redeliver(ctx context.Context, endpointID, id int64) (*models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries SET
+			status = 'pending', next_attempt_at = NOW(), attempts = 0,
+			last_status_code = NULL, last_error = NULL, delivered_at = NULL
+		WHERE id = $1 AND endpoint_id = $2
+		RETURNING ` + deliveryColumns
+
+	delivery := &models.WebhookDelivery{}
+	err := scanDelivery(s.Db.QueryRowContext(ctx, query, id, endpointID), delivery)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to redeliver webhook", "error", err, "id", id)
+		return nil, err
+	}
+
+	return delivery, nil
+}
@@ -0,0 +1,138 @@
+// Package linkhealth periodically checks that link destinations still respond
+package linkhealth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"shawty-ur/api/safehttp"
+)
+
+// userAgent identifies the checker to destination servers
+const userAgent = "shawty-ur-linkcheck/1.0 (+link health monitor)"
+
+// maxBodyBytes caps how much of a GET response is read before closing it
+const maxBodyBytes = 16 * 1024
+
+// Result is the outcome of checking one destination
+type Result struct {
+	StatusCode int // 0 when no response was received
+	Latency    time.Duration
+	Err        error
+}
+
+// Healthy reports whether the destination answered without an error status
+func (r Result) Healthy() bool {
+	return r.Err == nil && r.StatusCode > 0 && r.StatusCode < 400
+}
+
+// Checker issues HEAD requests to destinations, falling back to GET for
+// servers that don't support HEAD, and never sends more than perHost
+// concurrent requests to the same host. Only public addresses are requested.
+type Checker struct {
+	client  *http.Client
+	perHost int
+
+	mu    sync.Mutex
+	hosts map[string]*hostSlots
+}
+
+// hostSlots limits the concurrent requests to one host. It's dropped from
+// Checker.hosts once no check holds or waits for it, so hosts that aren't
+// being checked don't take up memory.
+type hostSlots struct {
+	slots chan struct{}
+	users int // Checks holding or waiting for a slot, guarded by Checker.mu
+}
+
+// NewChecker creates a checker with a per-request timeout
+func NewChecker(timeout time.Duration, perHost int) *Checker {
+	if perHost < 1 {
+		perHost = 1
+	}
+	return &Checker{
+		client:  safehttp.NewClient(timeout),
+		perHost: perHost,
+		hosts:   make(map[string]*hostSlots),
+	}
+}
+
+// Check requests rawURL and reports the final status code after redirects
+func (c *Checker) Check(ctx context.Context, rawURL string) Result {
+	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Result{Err: err}
+	}
+
+	release, err := c.acquire(ctx, u.Hostname())
+	if err != nil {
+		return Result{Err: err}
+	}
+	defer release()
+
+	start := time.Now()
+	status, err := c.do(ctx, http.MethodHead, rawURL)
+	// Plenty of servers reject or mishandle HEAD; ask again with GET
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented || status == http.StatusForbidden || status == http.StatusNotFound) {
+		start = time.Now()
+		status, err = c.do(ctx, http.MethodGet, rawURL)
+	}
+	return Result{StatusCode: status, Latency: time.Since(start), Err: err}
+}
+
+func (c *Checker) do(ctx context.Context, method, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyBytes))
+	return resp.StatusCode, nil
+}
+
+// acquire takes one of the host's request slots, waiting for a free one
+func (c *Checker) acquire(ctx context.Context, host string) (func(), error) {
+	c.mu.Lock()
+	h, ok := c.hosts[host]
+	if !ok {
+		h = &hostSlots{slots: make(chan struct{}, c.perHost)}
+		c.hosts[host] = h
+	}
+	h.users++
+	c.mu.Unlock()
+
+	select {
+	case h.slots <- struct{}{}:
+		return func() {
+			<-h.slots
+			c.done(host, h)
+		}, nil
+	case <-ctx.Done():
+		c.done(host, h)
+		return nil, ctx.Err()
+	}
+}
+
+// done forgets the host once no check holds or waits for its slots
+func (c *Checker) done(host string, h *hostSlots) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h.users--
+	if h.users == 0 {
+		delete(c.hosts, host)
+	}
+}
@@ -0,0 +1,53 @@
+package linkhealth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"shawty-ur/api/safehttp"
+)
+
+func TestCheckRefusesPrivateDestinations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("checker reached a loopback destination")
+	}))
+	defer server.Close()
+
+	checker := NewChecker(5*time.Second, 2)
+	result := checker.Check(context.Background(), server.URL)
+	if !errors.Is(result.Err, safehttp.ErrForbiddenAddress) {
+		t.Fatalf("Check(%s) error = %v, want %v", server.URL, result.Err, safehttp.ErrForbiddenAddress)
+	}
+	if result.Healthy() {
+		t.Error("refused destination reported healthy")
+	}
+}
+
+func TestAcquireForgetsIdleHosts(t *testing.T) {
+	checker := NewChecker(time.Second, 1)
+	ctx := context.Background()
+
+	release, err := checker.acquire(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second check waits for the only slot until its context is done
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := checker.acquire(waitCtx, "example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire on a busy host = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if len(checker.hosts) != 1 {
+		t.Fatalf("hosts = %d while a slot is held, want 1", len(checker.hosts))
+	}
+
+	release()
+	if len(checker.hosts) != 0 {
+		t.Errorf("hosts = %d after release, want 0", len(checker.hosts))
+	}
+}
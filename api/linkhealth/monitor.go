@@ -0,0 +1,166 @@
+package linkhealth
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"time"
+
+	"shawty-ur/api/health"
+	"shawty-ur/api/helper"
+	"shawty-ur/api/logging"
+	"shawty-ur/api/models"
+	"shawty-ur/api/webhook"
+	"shawty-ur/config"
+)
+
+const (
+	// pollInterval is how often the monitor looks for links due for a check
+	pollInterval = time.Minute
+	// staleAfter is how long the monitor may go without progress before
+	// readiness reports it stale
+	staleAfter = 15 * time.Minute
+	// batchSize is how many links are claimed per poll
+	batchSize = 100
+	// claimLease keeps other replicas off a claimed link while it's being checked
+	claimLease = 15 * time.Minute
+
+	// failureThreshold is how many failed checks in a row flag a link broken
+	failureThreshold = 3
+	// retryBase is the delay before re-checking a failing link, doubled on
+	// every further failure up to the regular check interval
+	retryBase = 5 * time.Minute
+)
+
+// Monitor checks link destinations on a schedule, records the outcome on the
+// link, flags it broken when it keeps failing and sends link.broken to the
+// owner's webhooks when it does
+type Monitor struct {
+	cfg       config.LinkHealthConfig
+	db        *sql.DB
+	links     *helper.LinkStore
+	checker   *Checker
+	heartbeat *health.Heartbeat
+}
+
+// NewMonitor creates a monitor for the links stored in db
+func NewMonitor(db *sql.DB, cfg config.LinkHealthConfig) *Monitor {
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	return &Monitor{
+		cfg:       cfg,
+		db:        db,
+		links:     helper.NewLinkStore(db),
+		checker:   NewChecker(cfg.Timeout, cfg.PerHost),
+		heartbeat: health.NewHeartbeat("link_health", staleAfter),
+	}
+}
+
+// Heartbeat tells whether the monitor is still making progress
+func (m *Monitor) Heartbeat() *health.Heartbeat {
+	return m.heartbeat
+}
+
+// Run checks due links until ctx is done. It does nothing when the check
+// interval is 0.
+func (m *Monitor) Run(ctx context.Context) {
+	if m.cfg.Interval <= 0 {
+		slog.InfoContext(ctx, "Link health monitor disabled")
+		m.heartbeat.Disable()
+		return
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while there's a backlog, then wait for the next poll
+		for {
+			n, err := m.checkDue(ctx)
+			if err == nil {
+				m.heartbeat.Beat()
+			}
+			if err != nil || n < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkDue checks one batch of due links and returns how many there were
+func (m *Monitor) checkDue(ctx context.Context) (int, error) {
+	links, err := m.links.ClaimHealthChecks(ctx, batchSize, claimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	sem := make(chan struct{}, m.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for _, link := range links {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			m.check(ctx, link)
+		}()
+	}
+	wg.Wait()
+
+	if len(links) > 0 {
+		slog.InfoContext(ctx, "Checked link destinations", "count", len(links))
+	}
+	return len(links), nil
+}
+
+// check checks a single link and records the outcome
+func (m *Monitor) check(ctx context.Context, link *models.Link) {
+	result := m.checker.Check(ctx, link.OriginalURL)
+	now := time.Now()
+
+	wasBroken := link.Health.Broken
+	health := models.LinkHealth{CheckedAt: &now}
+	if result.StatusCode > 0 {
+		health.StatusCode = &result.StatusCode
+		latency := result.Latency.Milliseconds()
+		health.LatencyMs = &latency
+	}
+	if result.Err != nil {
+		msg := result.Err.Error()
+		health.Error = &msg
+	}
+
+	next := m.cfg.Interval
+	if !result.Healthy() {
+		health.Failures = link.Health.Failures + 1
+		health.Broken = health.Failures >= failureThreshold
+		next = m.retryDelay(health.Failures)
+	}
+	link.Health = health
+
+	if err := m.links.RecordHealth(ctx, link.ID, health, now.Add(next)); err != nil {
+		return
+	}
+
+	if health.Broken && !wasBroken {
+		slog.WarnContext(ctx, "Link destination is broken", "short_code", link.ShortCode, "host", logging.URLHost(link.OriginalURL),
+			"status", result.StatusCode, "error", result.Err)
+		webhook.Publish(ctx, m.db, link.UserID, models.EventLinkBroken, map[string]any{"link": link})
+	}
+}
+
+// retryDelay backs off exponentially from retryBase, capped at the check interval
+func (m *Monitor) retryDelay(failures int) time.Duration {
+	delay := retryBase
+	for i := 1; i < failures && delay < m.cfg.Interval; i++ {
+		delay *= 2
+	}
+	return min(delay, m.cfg.Interval)
+}
@@ -0,0 +1,55 @@
+// Package live fans click events out to dashboards over Redis pub/sub, so a
+// visitor redirected by one replica shows up on streams served by any other
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"shawty-ur/api/models"
+	"shawty-ur/api/useragent"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Event is what a live stream shows of a click. It deliberately leaves out
+// the IP address and full referrer.
+type Event struct {
+	Timestamp    time.Time `json:"timestamp"`
+	Country      string    `json:"country,omitempty"`
+	ReferrerHost string    `json:"referrer_host,omitempty"`
+	Device       string    `json:"device"`
+	Bot          bool      `json:"bot"`
+}
+
+// NewEvent builds the live event of a click
+func NewEvent(click *models.Click) Event {
+	event := Event{
+		Timestamp:    click.ClickedAt.UTC(),
+		Country:      click.Country,
+		ReferrerHost: click.ReferrerDomain,
+		Device:       click.Device,
+		Bot:          click.IsBot,
+	}
+	if event.Device == "" {
+		event.Device = useragent.Parse(click.UserAgent).Device
+	}
+	return event
+}
+
+// Channel is the pub/sub channel carrying the clicks of a link
+func Channel(linkID int64) string {
+	return "live:clicks:" + strconv.FormatInt(linkID, 10)
+}
+
+// Publish sends a click to everyone streaming the link. Nothing is stored,
+// so this is cheap when nobody is listening.
+func Publish(ctx context.Context, rdb *redis.Client, linkID int64, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return rdb.Publish(ctx, Channel(linkID), payload).Err()
+}
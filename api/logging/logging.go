@@ -0,0 +1,112 @@
+// Package logging builds the service's slog logger: JSON or text records at
+// a configured level, carrying the request ID, user ID and trace of the
+// record's context, with secrets redacted before anything is written.
+//
+// Log with a context (slog.InfoContext and friends) wherever one is at hand,
+// so records can be tied back to the request that made them.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"shawty-ur/api/tracing"
+	"shawty-ur/config"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Record formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Formats lists the record formats
+var Formats = []string{FormatJSON, FormatText}
+
+// ParseLevel parses debug, info, warn or error, in any case
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", level)
+	}
+	return l, nil
+}
+
+// New builds the logger described by cfg, writing to w
+func New(cfg config.LogConfig, w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:       cfg.Level,
+		ReplaceAttr: redact,
+	}
+
+	var handler slog.Handler
+	if strings.EqualFold(cfg.Format, FormatText) {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(contextHandler{tracing.NewLogHandler(handler)})
+}
+
+// contextHandler adds the request and user IDs of the record's context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := middleware.GetReqID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if id, ok := UserID(ctx); ok {
+		record.AddAttrs(slog.Int64("user_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+type userKey struct{}
+
+// userHolder is where the user of a request is kept once they are
+// authenticated. The access log puts an empty one in the request context, so
+// the user found by auth middleware further in is known when the request is
+// logged on the way out.
+type userHolder struct {
+	id int64
+	ok bool
+}
+
+// WithRequestUser prepares ctx to carry the user a request is authenticated
+// as, for records logged after the handler has run
+func WithRequestUser(ctx context.Context) context.Context {
+	return context.WithValue(ctx, userKey{}, &userHolder{})
+}
+
+// WithUserID tags records logged with ctx, and the request's access log
+// entry, with the user ID
+func WithUserID(ctx context.Context, id int64) context.Context {
+	if holder, ok := ctx.Value(userKey{}).(*userHolder); ok {
+		holder.id, holder.ok = id, true
+		return ctx
+	}
+	return context.WithValue(ctx, userKey{}, &userHolder{id: id, ok: true})
+}
+
+// UserID returns the user ID ctx was tagged with
+func UserID(ctx context.Context) (int64, bool) {
+	if holder, ok := ctx.Value(userKey{}).(*userHolder); ok && holder.ok {
+		return holder.id, true
+	}
+	return 0, false
+}
@@ -1,17 +1,30 @@
 package main
 
 import (
-	"fmt"
-	"log"
+	"context"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"shawty-ur/api/auth"
+	"shawty-ur/api/bots"
+	"shawty-ur/api/deeplink"
+	"shawty-ur/api/geoip"
+	"shawty-ur/api/health"
+	"shawty-ur/api/linkhealth"
+	"shawty-ur/api/logging"
+	"shawty-ur/api/privacy"
+	"shawty-ur/api/referrer"
+	"shawty-ur/api/rollup"
 	"shawty-ur/api/routes"
+	"shawty-ur/api/tracing"
 	"shawty-ur/api/utils/db"
 	"shawty-ur/api/utils/redisUtil"
+	"shawty-ur/api/webhook"
 	"shawty-ur/app"
 	"shawty-ur/config"
 
@@ -21,11 +34,34 @@ import (
 func main() {
 	workingDir, err := os.Getwd()
 	if err != nil {
-		log.Fatalf("Error getting working dir %s", err)
+		slog.Error("Error getting working dir !!! ", slog.Any("err", err))
+		os.Exit(1)
 	}
 	envPath := filepath.Join(workingDir, "..", ".env")
-	if err := godotenv.Load(envPath); err != nil {
-		fmt.Printf("ERR !!! Cannot load .env %s", err)
+	envErr := godotenv.Load(envPath)
+
+	// Logging, e.g. LOG_FORMAT=text and LOG_LEVEL=debug. Every record, from
+	// slog's package functions too, goes through this one handler.
+	logConfig := config.LogConfig{Format: logging.FormatJSON, Level: slog.LevelInfo}
+	if format := os.Getenv("LOG_FORMAT"); format != "" {
+		if !slices.Contains(logging.Formats, format) {
+			slog.Error("Invalid LOG_FORMAT !!! ", "format", format, "formats", logging.Formats)
+			os.Exit(1)
+		}
+		logConfig.Format = format
+	}
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		parsed, err := logging.ParseLevel(level)
+		if err != nil {
+			slog.Error("Invalid LOG_LEVEL !!! ", slog.Any("err", err))
+			os.Exit(1)
+		}
+		logConfig.Level = parsed
+	}
+	logger := logging.New(logConfig, os.Stderr)
+	slog.SetDefault(logger)
+	if envErr != nil {
+		slog.Warn("Cannot load .env", "path", envPath, "error", envErr)
 	}
 
 	dbConfig := db.DbConfig{
@@ -48,13 +84,150 @@ func main() {
 		DB:       redisDB,
 	}
 
+	// GeoIP database reload interval, e.g. "1m"
+	geoIPReload := time.Minute
+	if interval := os.Getenv("GEOIP_RELOAD_INTERVAL"); interval != "" {
+		if parsed, err := time.ParseDuration(interval); err == nil {
+			geoIPReload = parsed
+		}
+	}
+
+	geoIPConfig := config.GeoIPConfig{
+		DbPath:         os.Getenv("GEOIP_DB_PATH"),
+		ReloadInterval: geoIPReload,
+	}
+
+	// HTTPS with ACME certificates for branded domains (optional)
+	tlsEnabled, _ := strconv.ParseBool(os.Getenv("TLS_ENABLED"))
+	tlsConfig := config.TLSConfig{
+		Enabled:      tlsEnabled,
+		HTTPAddr:     os.Getenv("TLS_HTTP_ADDR"),
+		Email:        os.Getenv("ACME_EMAIL"),
+		DirectoryURL: os.Getenv("ACME_DIRECTORY_URL"),
+		CACertPath:   os.Getenv("ACME_CA_CERT"),
+	}
+	if tlsConfig.HTTPAddr == "" {
+		tlsConfig.HTTPAddr = ":80"
+	}
+	for _, host := range strings.Split(os.Getenv("TLS_HOSTS"), ",") {
+		if host = strings.TrimSpace(strings.ToLower(host)); host != "" {
+			tlsConfig.Hosts = append(tlsConfig.Hosts, host)
+		}
+	}
+
+	// Destination health checks, e.g. LINK_HEALTH_INTERVAL=24h; 0 disables them
+	linkHealthConfig := config.LinkHealthConfig{
+		Interval:    24 * time.Hour,
+		Timeout:     10 * time.Second,
+		Concurrency: 10,
+		PerHost:     2,
+	}
+	if interval := os.Getenv("LINK_HEALTH_INTERVAL"); interval != "" {
+		if parsed, err := time.ParseDuration(interval); err == nil {
+			linkHealthConfig.Interval = parsed
+		}
+	}
+	if timeout := os.Getenv("LINK_HEALTH_TIMEOUT"); timeout != "" {
+		if parsed, err := time.ParseDuration(timeout); err == nil {
+			linkHealthConfig.Timeout = parsed
+		}
+	}
+	if n, err := strconv.Atoi(os.Getenv("LINK_HEALTH_CONCURRENCY")); err == nil && n > 0 {
+		linkHealthConfig.Concurrency = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("LINK_HEALTH_PER_HOST")); err == nil && n > 0 {
+		linkHealthConfig.PerHost = n
+	}
+
+	// Analytics retention per tier, e.g. ANALYTICS_RAW_RETENTION=2160h; 0 keeps a tier forever
+	analyticsConfig := config.AnalyticsConfig{
+		RawRetention:    90 * 24 * time.Hour,
+		HourlyRetention: 400 * 24 * time.Hour,
+	}
+	for env, retention := range map[string]*time.Duration{
+		"ANALYTICS_RAW_RETENTION":    &analyticsConfig.RawRetention,
+		"ANALYTICS_HOURLY_RETENTION": &analyticsConfig.HourlyRetention,
+		"ANALYTICS_DAILY_RETENTION":  &analyticsConfig.DailyRetention,
+	} {
+		if value := os.Getenv(env); value != "" {
+			if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+				*retention = parsed
+			}
+		}
+	}
+	// Daily rollups are summed from the hourly ones, which must outlive the day in progress
+	if analyticsConfig.HourlyRetention > 0 && analyticsConfig.HourlyRetention < 48*time.Hour {
+		analyticsConfig.HourlyRetention = 48 * time.Hour
+	}
+
+	// Visitor privacy, e.g. PRIVACY_MODE=hash and PRIVACY_OPT_OUT=skip
+	privacyConfig := config.PrivacyConfig{Mode: privacy.ModeTruncate, OptOut: privacy.OptOutMinimize}
+	if mode := os.Getenv("PRIVACY_MODE"); mode != "" {
+		if !privacy.ValidMode(mode) {
+			slog.Error("Invalid PRIVACY_MODE !!! ", "mode", mode, "modes", privacy.Modes)
+			os.Exit(1)
+		}
+		privacyConfig.Mode = mode
+	}
+	if optOut := os.Getenv("PRIVACY_OPT_OUT"); optOut != "" {
+		if !slices.Contains(privacy.OptOutActions, optOut) {
+			slog.Error("Invalid PRIVACY_OPT_OUT !!! ", "action", optOut, "actions", privacy.OptOutActions)
+			os.Exit(1)
+		}
+		privacyConfig.OptOut = optOut
+	}
+
+	// Prometheus metrics on a listener of their own, loopback only unless
+	// METRICS_ADDR says otherwise (e.g. :9090 in a container); off disables it
+	metricsConfig := config.MetricsConfig{Addr: "127.0.0.1:9090"}
+	if addr := os.Getenv("METRICS_ADDR"); addr == "off" {
+		metricsConfig.Addr = ""
+	} else if addr != "" {
+		metricsConfig.Addr = addr
+	}
+
+	// OpenTelemetry tracing, e.g. TRACING_EXPORTER=otlp with OTEL_EXPORTER_OTLP_ENDPOINT
+	tracingConfig := config.TracingConfig{
+		Exporter:    tracing.ExporterNone,
+		ServiceName: "shawty-ur",
+		SampleRatio: 1,
+	}
+	if exporter := os.Getenv("TRACING_EXPORTER"); exporter != "" {
+		if !slices.Contains(tracing.Exporters, exporter) {
+			slog.Error("Invalid TRACING_EXPORTER !!! ", "exporter", exporter, "exporters", tracing.Exporters)
+			os.Exit(1)
+		}
+		tracingConfig.Exporter = exporter
+	}
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		tracingConfig.ServiceName = name
+	}
+	if ratio, err := strconv.ParseFloat(os.Getenv("TRACING_SAMPLE_RATIO"), 64); err == nil && ratio >= 0 && ratio <= 1 {
+		tracingConfig.SampleRatio = ratio
+	}
+
 	cfg := config.Config{
 		DbConfig:    dbConfig,
 		RedisConfig: redisConfig,
+		GeoIPConfig: geoIPConfig,
+		TLSConfig:   tlsConfig,
+		LinkHealth:  linkHealthConfig,
+		Analytics:   analyticsConfig,
+		Privacy:     privacyConfig,
+		Metrics:     metricsConfig,
+		Tracing:     tracingConfig,
+		Log:         logConfig,
 		JwtSecret:   os.Getenv("JWT_SECRET"),
 		Addr:        os.Getenv("ADDR"),
 	}
 
+	// Initialize tracing before the instrumented clients below
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		slog.Error("Error setting up tracing !!! ", slog.Any("err", err))
+		os.Exit(1)
+	}
+
 	// Initialize PostgreSQL connection
 	dbConn, err := db.New(cfg.DbConfig)
 	if err != nil {
@@ -69,6 +242,48 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Initialize GeoIP database (optional)
+	geoIP, err := geoip.Open(cfg.GeoIPConfig.DbPath)
+	if err != nil {
+		slog.Error("Error loading GeoIP database !!! ", slog.Any("err", err))
+		os.Exit(1)
+	}
+	go geoIP.Watch(context.Background(), cfg.GeoIPConfig.ReloadInterval)
+
+	// Initialize app association files for deep links (optional)
+	appLinks, err := deeplink.LoadAssociations(os.Getenv("APP_LINKS_CONFIG"))
+	if err != nil {
+		slog.Error("Error loading app links config !!! ", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	// Initialize the bot classifier, with crawler reverse DNS suffixes from
+	// BOT_RDNS_FILE or the built-in list
+	botClassifier, err := bots.Load(os.Getenv("BOT_RDNS_FILE"))
+	if err != nil {
+		slog.Error("Error loading bot reverse DNS list !!! ", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	// Initialize the referrer rules, from REFERRER_RULES_FILE or the built-in list
+	referrerRules, err := referrer.Load(os.Getenv("REFERRER_RULES_FILE"))
+	if err != nil {
+		slog.Error("Error loading referrer rules !!! ", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	// Start checking link destinations in the background
+	linkHealthMonitor := linkhealth.NewMonitor(dbConn, cfg.LinkHealth)
+	go linkHealthMonitor.Run(context.Background())
+
+	// Roll up click analytics and prune them past retention in the background
+	rollupAggregator := rollup.NewAggregator(dbConn, cfg.Analytics)
+	go rollupAggregator.Run(context.Background())
+
+	// Send queued webhook deliveries in the background
+	webhookDispatcher := webhook.NewDispatcher(dbConn)
+	go webhookDispatcher.Run(context.Background())
+
 	// Initialize OAuth configuration
 	oauthConfig := auth.NewOAuthConfig(
 		os.Getenv("GOOGLE_CLIENT_ID"),
@@ -81,26 +296,55 @@ func main() {
 
 	application := &app.Application{
 		Config:       cfg,
+		Logger:       logger,
 		DbConnector:  dbConn,
 		RedisClient:  redisClient,
 		OAuthConfig:  oauthConfig,
 		SessionStore: sessionStore,
+		GeoIP:        geoIP,
+		AppLinks:     appLinks,
+		Bots:         botClassifier,
+		Referrers:    referrerRules,
+		Workers: []*health.Heartbeat{
+			linkHealthMonitor.Heartbeat(),
+			rollupAggregator.Heartbeat(),
+			webhookDispatcher.Heartbeat(),
+		},
 	}
 
 	// Register all route handlers
 	// Adding new routes is as simple as adding new RegisterXRoutes functions here
 	application.RegisterRoutes(
-		routes.RegisterHealthRoutes,
 		routes.RegisterUserRoutes,
 		routes.RegisterServiceRoutes,
 		routes.RegisterAuthRoutes,
+		routes.RegisterLinkRoutes,
+		routes.RegisterUTMRoutes,
+		routes.RegisterTeamRoutes,
+		routes.RegisterDomainRoutes,
+		routes.RegisterWebhookRoutes,
+		routes.RegisterAnalyticsRoutes,
 	)
 	application.RegisterSoloRoutes(
+		routes.RegisterHealthRoutes,
+		routes.RegisterAppLinkRoutes,
 		routes.RegisterResolveRoutes,
 	)
+	application.RegisterInternalRoutes(
+		routes.RegisterInternalHealthRoutes,
+	)
 
 	mux := application.Mount()
-	if err := application.Run(mux); err != nil {
-		log.Fatalf("Error starting server: %s", err)
+	err = application.Run(mux)
+
+	// Flush the spans still buffered before exiting
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
+	}
+	if err != nil {
+		slog.Error("Error starting server !!! ", slog.Any("err", err))
+		os.Exit(1)
 	}
 }
@@ -5,11 +5,18 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+// Caches counted by CacheHits and CacheMisses
+const (
+	CacheLinks    = "links"    // Short code to destination lookups
+	CacheQR       = "qr"       // Rendered QR code images
+	CacheWebhooks = "webhooks" // Whether a user has link.clicked endpoints
+)
+
 var (
 	HttpRequestsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "http_requests",
-			Help: "Total Number of HTTP requests",
+			Name: "http_requests_total",
+			Help: "Total number of HTTP requests",
 		},
 		[]string{"method", "endpoint", "status"},
 	)
@@ -26,51 +33,55 @@ var (
 	UrlsShortenedTotal = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Name: "urls_shortened_total",
-			Help: "total number of urls shortened",
+			Help: "Total number of URLs shortened",
 		},
 	)
 
 	ActiveConnections = promauto.NewGauge(
 		prometheus.GaugeOpts{
-			Name: "Active_Connections",
-			Help: "Number of active connections",
+			Name: "active_connections",
+			Help: "Number of HTTP requests in flight",
 		},
 	)
 
 	UrlsResolvedTotal = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Name: "urls_resolved_total",
-			Help: "total_urls_resolved",
+			Help: "Total number of short URLs resolved",
 		},
 	)
 
 	DatabaseQueryDuration = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name: "database_query_duration in seconds",
-			Help: "Duration of each DB query in seconds",
+			Name:    "database_query_duration_seconds",
+			Help:    "Duration of each DB query in seconds",
+			Buckets: prometheus.DefBuckets,
 		},
 		[]string{"query_type"},
 	)
 
 	RedisCacheDuration = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name: "redis_operation_duration_seconds",
-			Help: "duratoin of each redis operation in seconds",
+			Name:    "redis_operation_duration_seconds",
+			Help:    "Duration of each Redis operation in seconds",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
 		},
 		[]string{"operation"},
 	)
 
-	CacheHits = promauto.NewCounter(
+	CacheHits = promauto.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "Cache_hits_total",
+			Name: "cache_hits_total",
 			Help: "Total number of cache hits",
 		},
+		[]string{"cache"},
 	)
 
-	CacheMisses = promauto.NewCounter(
+	CacheMisses = promauto.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "Cache_miss_total",
-			Help: "Total numbe of cache misses",
+			Name: "cache_misses_total",
+			Help: "Total number of cache misses",
 		},
+		[]string{"cache"},
 	)
 )
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"shawty-ur/api/auth"
+	"shawty-ur/api/logging"
 	"shawty-ur/api/utils"
 )
 
@@ -28,8 +29,9 @@ func RequireAuth(sessionStore *auth.SessionStore) func(http.Handler) http.Handle
 				return
 			}
 
-			// Add session data to request context
+			// Add session data to request context, and tag its log records with the user
 			ctx := context.WithValue(r.Context(), UserContextKey, session)
+			ctx = logging.WithUserID(ctx, session.UserID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
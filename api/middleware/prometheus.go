@@ -7,18 +7,9 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
 )
 
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-}
-
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
-}
-
 func PrometheusMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		start := time.Now()
@@ -27,14 +18,13 @@ func PrometheusMiddleware(next http.Handler) http.Handler {
 		metrics.ActiveConnections.Inc()
 		defer metrics.ActiveConnections.Dec()
 
-		wrapped := &responseWriter{
-			ResponseWriter: w,
-			statusCode:     http.StatusOK,
-		}
+		// chi's wrapper keeps http.Flusher working for the live click stream
+		wrapped := chimiddleware.NewWrapResponseWriter(w, req.ProtoMajor)
 
 		next.ServeHTTP(wrapped, req)
 
-		//Get the full route pattern (e.g /api/v1/users/{id}...)
+		//Get the full route pattern (e.g /api/v1/users/{id}...), which is
+		//only complete once the request has been routed
 		routePattern := chi.RouteContext(req.Context()).RoutePattern()
 		if routePattern == "" {
 			routePattern = "unknown"
@@ -43,7 +33,11 @@ func PrometheusMiddleware(next http.Handler) http.Handler {
 		//Record metrics
 
 		duration := time.Since(start).Seconds()
-		status := strconv.Itoa(wrapped.statusCode)
+		statusCode := wrapped.Status()
+		if statusCode == 0 {
+			statusCode = http.StatusOK
+		}
+		status := strconv.Itoa(statusCode)
 
 		metrics.HttpRequestsTotal.WithLabelValues(
 			req.Method,
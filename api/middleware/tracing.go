@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware starts a server span for every request, continuing the
+// trace of a W3C traceparent header. The span starts out named after the
+// method alone, since the route isn't known yet, and is renamed after the
+// chi route pattern once the request has been routed, so /{url} is one span
+// name rather than one per short code.
+func TracingMiddleware(next http.Handler) http.Handler {
+	routed := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(w, req)
+		if routePattern := chiRoutePattern(req); routePattern != "" {
+			span := trace.SpanFromContext(req.Context())
+			span.SetName(req.Method + " " + routePattern)
+			span.SetAttributes(semconv.HTTPRoute(routePattern))
+		}
+	})
+	return otelhttp.NewHandler(routed, "http.request",
+		// otelhttp names the span again after serving when chi set
+		// req.Pattern, so the formatter must agree with the name set above
+		otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+			if routePattern := chiRoutePattern(req); routePattern != "" {
+				return req.Method + " " + routePattern
+			}
+			return req.Method
+		}),
+	)
+}
+
+// chiRoutePattern is the pattern of the route a request matched, if any
+func chiRoutePattern(req *http.Request) string {
+	if rctx := chi.RouteContext(req.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingMiddlewareNamesSpansAfterRoutes(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	r := chi.NewRouter()
+	r.Use(TracingMiddleware)
+	r.Get("/{url}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Get("/links/{code}/stats", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/launch", "GET /{url}"},
+		{"/api/v1/links/launch/stats", "GET /api/v1/links/{code}/stats"},
+	}
+	for _, tt := range tests {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		spans := recorder.Ended()
+		if got := spans[len(spans)-1].Name(); got != tt.want {
+			t.Errorf("span name for %s = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
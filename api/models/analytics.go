@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+// Click is a single resolved redirect, stored in url_analytics
+type Click struct {
+	ID        int64  `json:"id"`
+	URLID     int64  `json:"url_id"`
+	VariantID *int64 `json:"variant_id,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"` // Truncated, or empty, per the privacy mode
+	IPHash    string `json:"ip_hash,omitempty"`    // Set instead of IPAddress in the hash privacy mode
+	UserAgent string `json:"user_agent,omitempty"`
+	Referrer  string `json:"referrer,omitempty"`
+	Country   string `json:"country,omitempty"`
+	City      string `json:"city,omitempty"`
+	ViaQR     bool   `json:"via_qr"`
+	IsBot     bool   `json:"is_bot"`
+	BotReason string `json:"bot_reason,omitempty"` // e.g. "user_agent:slackbot" or "prefetch"
+	// Parsed from UserAgent at ingest; see package useragent
+	Browser        string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OS             string `json:"os,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	Device         string `json:"device,omitempty"`
+	// Normalized referrer; see package referrer
+	ReferrerDomain   string `json:"referrer_domain,omitempty"`
+	ReferrerCategory string `json:"referrer_category,omitempty"`
+	// Campaign parameters on the short link request itself
+	UTM       UTMParams `json:"utm"`
+	ClickedAt time.Time `json:"clicked_at"`
+}
+
+// Breakdown is the number of clicks for one value of a dimension, such as a
+// browser or a device type
+type Breakdown struct {
+	Value  string `json:"value"`
+	Clicks int64  `json:"clicks"`
+}
+
+// ClickTotals counts clicks, split into people and crawlers, unfurlers and
+// prefetches
+type ClickTotals struct {
+	Clicks      int64 `json:"clicks"`
+	HumanClicks int64 `json:"human_clicks"`
+	BotClicks   int64 `json:"bot_clicks"`
+	QRScans     int64 `json:"qr_scans"`
+}
+
+// SeriesPoint is the number of clicks in one bucket of a time series
+type SeriesPoint struct {
+	Bucket      time.Time `json:"bucket"`
+	Clicks      int64     `json:"clicks"`
+	HumanClicks int64     `json:"human_clicks"`
+}
+
+// LinkClicks is a link along with its human clicks over a time range
+type LinkClicks struct {
+	ID          int64  `json:"id"`
+	ShortCode   string `json:"short_code"`
+	Domain      string `json:"domain,omitempty"` // Branded hostname, empty for the default domain
+	OriginalURL string `json:"original_url"`
+	Clicks      int64  `json:"clicks"`
+}
+
+// ClickGrowth is the change in clicks from the previous period, in percent.
+// Fields are null when the previous period had no clicks.
+type ClickGrowth struct {
+	Clicks      *float64 `json:"clicks"`
+	HumanClicks *float64 `json:"human_clicks"`
+}
+
+// AccountStats summarises the clicks of all of a user's or team's links over
+// a time range
+type AccountStats struct {
+	TeamID   *int64    `json:"team_id,omitempty"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Interval string    `json:"interval"`
+	ClickTotals
+	// Totals of the period of the same length right before From
+	Previous ClickTotals    `json:"previous"`
+	Growth   ClickGrowth    `json:"growth"`
+	Series   []*SeriesPoint `json:"series"`
+	// Most human clicks first
+	TopLinks     []*LinkClicks `json:"top_links"`
+	TopCountries []*Breakdown  `json:"top_countries"`
+	TopReferrers []*Breakdown  `json:"top_referrers"`
+	TopSources   []*Breakdown  `json:"top_sources"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"shawty-ur/api/deeplink"
+	"shawty-ur/api/routing"
+)
+
+// Link represents a shortened URL stored in the urls table
+type Link struct {
+	ID          int64      `json:"id"`
+	UserID      *int64     `json:"user_id,omitempty"`   // NULL for anonymous links
+	DomainID    *int64     `json:"domain_id,omitempty"` // NULL for the default domain
+	OriginalURL string     `json:"original_url"`
+	ShortCode   string     `json:"short_code"`
+	CustomShort bool       `json:"custom_short"`
+	Clicks      int64      `json:"clicks"`
+	Title       *string    `json:"title,omitempty"` // Destination page title (nullable)
+	Tags        []string   `json:"tags"`
+	Notes       *string    `json:"notes,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	// Redirect passthrough options
+	ForwardQuery bool `json:"forward_query"`
+	ForwardPath  bool `json:"forward_path"`
+	// Conditional redirects, evaluated in order before falling back to OriginalURL
+	RoutingRules routing.Rules `json:"routing_rules,omitempty"`
+	// App URLs tried before the web destination on mobile (nullable)
+	DeepLink *deeplink.Config `json:"deep_link,omitempty"`
+	// How visitor IPs are anonymized, stricter than the server setting (nullable)
+	PrivacyMode *string `json:"privacy_mode,omitempty"`
+	// Whether the link has split-test variants
+	HasVariants bool `json:"has_variants"`
+	// Latest destination health check
+	Health    LinkHealth `json:"health"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// LinkHealth is the outcome of the latest check of a link's destination
+type LinkHealth struct {
+	StatusCode *int       `json:"status_code,omitempty"` // Final status after redirects
+	LatencyMs  *int64     `json:"latency_ms,omitempty"`
+	Error      *string    `json:"error,omitempty"` // Set when no response was received
+	CheckedAt  *time.Time `json:"checked_at,omitempty"`
+	Failures   int        `json:"consecutive_failures"`
+	Broken     bool       `json:"broken"` // Failed enough checks in a row to be flagged
+}
+
+// LinkSearchResult is a link matched by a search query along with its relevance
+type LinkSearchResult struct {
+	Link
+	Rank float64 `json:"rank"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Team roles
+const (
+	TeamRoleOwner  = "owner"
+	TeamRoleMember = "member"
+)
+
+// Team is a group of users sharing branded domains
+type Team struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role,omitempty"` // The requesting user's role, when listed for a user
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Domain is a branded hostname short links can be served from
+type Domain struct {
+	ID                int64      `json:"id"`
+	TeamID            int64      `json:"team_id"`
+	Hostname          string     `json:"hostname"`
+	VerificationToken string     `json:"verification_token"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// Verified reports whether the domain passed DNS verification
+func (d *Domain) Verified() bool {
+	return d.VerifiedAt != nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"log/slog"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -12,6 +13,16 @@ type Password struct {
 	Hash []byte
 }
 
+// String keeps passwords out of logs and formatted output
+func (Password) String() string {
+	return "[REDACTED]"
+}
+
+// LogValue keeps passwords out of structured logs
+func (p Password) LogValue() slog.Value {
+	return slog.StringValue(p.String())
+}
+
 // Set hashes a plain text password
 // func (p *Password) Set(text string) error {
 
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// Column limits from the utm_presets table, in characters
+const (
+	MaxUTMValueLength      = 255
+	MaxUTMPresetNameLength = 100
+)
+
+// UTMParams holds the campaign tracking parameters appended to a destination URL
+type UTMParams struct {
+	Source   string `json:"utm_source,omitempty"`
+	Medium   string `json:"utm_medium,omitempty"`
+	Campaign string `json:"utm_campaign,omitempty"`
+	Term     string `json:"utm_term,omitempty"`
+	Content  string `json:"utm_content,omitempty"`
+}
+
+// Values returns the non-empty parameters in their canonical order
+func (p UTMParams) Values() [][2]string {
+	all := [][2]string{
+		{"utm_source", p.Source},
+		{"utm_medium", p.Medium},
+		{"utm_campaign", p.Campaign},
+		{"utm_term", p.Term},
+		{"utm_content", p.Content},
+	}
+
+	values := make([][2]string, 0, len(all))
+	for _, kv := range all {
+		if kv[1] != "" {
+			values = append(values, kv)
+		}
+	}
+	return values
+}
+
+// Validate checks that no parameter is longer than MaxUTMValueLength
+func (p UTMParams) Validate() error {
+	for _, kv := range p.Values() {
+		if utf8.RuneCountInString(kv[1]) > MaxUTMValueLength {
+			return fmt.Errorf("%s must be at most %d characters", kv[0], MaxUTMValueLength)
+		}
+	}
+	return nil
+}
+
+// Merge returns p with any empty field filled in from fallback
+func (p UTMParams) Merge(fallback UTMParams) UTMParams {
+	pick := func(a, b string) string {
+		if a != "" {
+			return a
+		}
+		return b
+	}
+	return UTMParams{
+		Source:   pick(p.Source, fallback.Source),
+		Medium:   pick(p.Medium, fallback.Medium),
+		Campaign: pick(p.Campaign, fallback.Campaign),
+		Term:     pick(p.Term, fallback.Term),
+		Content:  pick(p.Content, fallback.Content),
+	}
+}
+
+// UTMPreset is a named, reusable set of UTM parameters saved by a user
+type UTMPreset struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	UTM       UTMParams `json:"utm"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// LinkVariant is one weighted destination of an A/B split-test link
+type LinkVariant struct {
+	ID          int64     `json:"id"`
+	URLID       int64     `json:"url_id"`
+	Name        string    `json:"name"`
+	Destination string    `json:"destination"`
+	Weight      int       `json:"weight"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// VariantStats is a variant along with the human clicks attributed to it
+type VariantStats struct {
+	LinkVariant
+	Clicks int64 `json:"clicks"`
+}
+
+// LinkStats summarises the clicks of a link over a time range
+type LinkStats struct {
+	ShortCode string    `json:"short_code"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Interval  string    `json:"interval"`
+	ClickTotals
+	// Estimated distinct visitors; see package visitors
+	UniqueVisitors int64 `json:"unique_visitors"`
+	// Clicks per bucket of Interval
+	Series []*SeriesPoint `json:"series"`
+	// Human clicks per device type, browser, OS and country, most clicks first
+	Devices   []*Breakdown `json:"devices"`
+	Browsers  []*Breakdown `json:"browsers"`
+	OS        []*Breakdown `json:"os"`
+	Countries []*Breakdown `json:"countries"`
+	// Human clicks per referrer domain, source category and campaign
+	Referrers    []*Breakdown    `json:"referrers"`
+	Sources      []*Breakdown    `json:"sources"`
+	UTMSources   []*Breakdown    `json:"utm_sources"`
+	UTMMediums   []*Breakdown    `json:"utm_mediums"`
+	UTMCampaigns []*Breakdown    `json:"utm_campaigns"`
+	Variants     []*VariantStats `json:"variants,omitempty"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook events
+const (
+	EventLinkCreated = "link.created"
+	EventLinkUpdated = "link.updated"
+	EventLinkDeleted = "link.deleted"
+	EventLinkExpired = "link.expired"
+	EventLinkClicked = "link.clicked"
+	EventLinkBroken  = "link.broken"
+)
+
+// WebhookEvents lists every event an endpoint can subscribe to
+var WebhookEvents = []string{
+	EventLinkCreated,
+	EventLinkUpdated,
+	EventLinkDeleted,
+	EventLinkExpired,
+	EventLinkClicked,
+	EventLinkBroken,
+}
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed" // Gave up after the last retry
+)
+
+// WebhookEndpoint is a URL events of the user's links are posted to
+type WebhookEndpoint struct {
+	ID     int64    `json:"id"`
+	UserID int64    `json:"user_id"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"` // Only returned when the endpoint is created
+	Events []string `json:"events"`
+	// Share of clicks sent as link.clicked, between 0 and 1
+	ClickSampleRate float64   `json:"click_sample_rate"`
+	Active          bool      `json:"active"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one event queued for, or delivered to, an endpoint
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	EndpointID     int64           `json:"endpoint_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
@@ -0,0 +1,110 @@
+// Package privacy anonymizes visitor IP addresses before they are stored or
+// logged, and honours Do-Not-Track and Global Privacy Control signals.
+//
+// The privacy mode is set for the whole server and can be made stricter, but
+// never looser, per link.
+package privacy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"shawty-ur/api/salt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Privacy modes, from least to most private
+const (
+	ModeOff      = "off"      // Store IP addresses as they are
+	ModeTruncate = "truncate" // Zero the host part: IPv4 to /24, IPv6 to /48
+	ModeHash     = "hash"     // Replace them with a hash salted per day
+)
+
+// Modes lists the privacy modes, from least to most private
+var Modes = []string{ModeOff, ModeTruncate, ModeHash}
+
+// What to do with the clicks of visitors who send DNT: 1 or Sec-GPC: 1
+const (
+	OptOutIgnore   = "ignore"   // Record them like any other click
+	OptOutMinimize = "minimize" // Count them, but without anything identifying
+	OptOutSkip     = "skip"     // Don't record them at all
+)
+
+// OptOutActions lists the ways opted-out clicks can be handled
+var OptOutActions = []string{OptOutIgnore, OptOutMinimize, OptOutSkip}
+
+// saltPrefix prefixes the Redis keys of the IP hashing salts
+const saltPrefix = "privacy:salt"
+
+// ValidMode reports whether mode is a known privacy mode
+func ValidMode(mode string) bool {
+	return slices.Contains(Modes, mode)
+}
+
+// Strictest returns the most private of the given modes, ignoring empty ones
+func Strictest(modes ...string) string {
+	strictest := ModeOff
+	for _, mode := range modes {
+		if slices.Index(Modes, mode) > slices.Index(Modes, strictest) {
+			strictest = mode
+		}
+	}
+	return strictest
+}
+
+// OptedOut reports whether the visitor asked not to be tracked
+func OptedOut(req *http.Request) bool {
+	return req.Header.Get("DNT") == "1" || req.Header.Get("Sec-GPC") == "1"
+}
+
+// Truncate zeroes the host part of an IP address, keeping the /24 of IPv4
+// and the /48 of IPv6 addresses. Anything that isn't an IP address is dropped.
+func Truncate(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// Hash replaces an IP address with an HMAC keyed by the salt of the day it
+// was seen, so the same address can be told apart within a day but not
+// linked across days or back to the address once the salt is gone
+func Hash(ctx context.Context, rdb *redis.Client, ip string, at time.Time) (string, error) {
+	if ip == "" {
+		return "", nil
+	}
+	key, err := salt.Daily(ctx, rdb, saltPrefix, at)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil)[:16]), nil
+}
+
+// AnonymizeAddr applies mode to a host:port address for logging. Hashing
+// needs the shared salt, so hashed addresses are left out of logs altogether.
+func AnonymizeAddr(addr, mode string) string {
+	switch mode {
+	case ModeOff:
+		return addr
+	case ModeHash:
+		return "-"
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return Truncate(host)
+}
@@ -0,0 +1,24 @@
+package privacy
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"203.0.113.57", "203.0.113.0"},
+		{"10.1.2.3", "10.1.2.0"},
+		{"::ffff:198.51.100.9", "198.51.100.0"},
+		{"2001:db8:85a3:8d3:1319:8a2e:370:7348", "2001:db8:85a3::"},
+		{"::1", "::"},
+		{"", ""},
+		{"not an ip", ""},
+		{"203.0.113.57:443", ""},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.ip); got != tt.want {
+			t.Errorf("Truncate(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package qr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Output formats
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+const (
+	minSize   = 64
+	maxSize   = 2048
+	maxMargin = 16
+)
+
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Options controls how a QR code is rendered
+type Options struct {
+	Format     string // png or svg
+	Size       int    // Width and height in pixels (PNG) or user units (SVG)
+	Level      string // Error correction level: L, M, Q or H
+	Margin     int    // Quiet zone around the code, in modules
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+// DefaultOptions is what ParseOptions starts from
+func DefaultOptions() Options {
+	return Options{
+		Format:     FormatPNG,
+		Size:       256,
+		Level:      "M",
+		Margin:     4,
+		Foreground: color.RGBA{0, 0, 0, 255},
+		Background: color.RGBA{255, 255, 255, 255},
+	}
+}
+
+// ParseOptions reads ?format=, ?size=, ?level=, ?margin=, ?fg= and ?bg= on
+// top of the defaults
+func ParseOptions(query url.Values) (Options, error) {
+	opts := DefaultOptions()
+
+	if v := query.Get("format"); v != "" {
+		opts.Format = strings.ToLower(v)
+		if opts.Format != FormatPNG && opts.Format != FormatSVG {
+			return opts, fmt.Errorf("format must be png or svg")
+		}
+	}
+	if v := query.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < minSize || size > maxSize {
+			return opts, fmt.Errorf("size must be between %d and %d", minSize, maxSize)
+		}
+		opts.Size = size
+	}
+	if v := query.Get("level"); v != "" {
+		opts.Level = strings.ToUpper(v)
+		if _, ok := levels[opts.Level]; !ok {
+			return opts, fmt.Errorf("level must be one of L, M, Q, H")
+		}
+	}
+	if v := query.Get("margin"); v != "" {
+		margin, err := strconv.Atoi(v)
+		if err != nil || margin < 0 || margin > maxMargin {
+			return opts, fmt.Errorf("margin must be between 0 and %d", maxMargin)
+		}
+		opts.Margin = margin
+	}
+
+	var err error
+	if v := query.Get("fg"); v != "" {
+		if opts.Foreground, err = parseHexColor(v); err != nil {
+			return opts, fmt.Errorf("fg: %w", err)
+		}
+	}
+	if v := query.Get("bg"); v != "" {
+		if opts.Background, err = parseHexColor(v); err != nil {
+			return opts, fmt.Errorf("bg: %w", err)
+		}
+	}
+	return opts, nil
+}
+
+// parseHexColor parses RRGGBB with an optional leading #
+func parseHexColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 3 {
+		return color.RGBA{}, fmt.Errorf("color must be a hex RRGGBB value")
+	}
+	return color.RGBA{b[0], b[1], b[2], 255}, nil
+}
+
+// ContentType returns the MIME type of the rendered image
+func (o Options) ContentType() string {
+	if o.Format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// cachedSizes are the sizes worth caching, since they're the ones asked for
+// most; any other size is rendered on every request
+var cachedSizes = map[int]bool{128: true, 256: true, 512: true, 1024: true}
+
+// Cacheable reports whether images rendered with these options may be
+// cached: the default colors and margin at one of a few common sizes. Anyone
+// can ask for a QR code, so caching every combination would let them fill
+// the cache.
+func (o Options) Cacheable() bool {
+	defaults := DefaultOptions()
+	return cachedSizes[o.Size] &&
+		o.Margin == defaults.Margin &&
+		o.Foreground == defaults.Foreground &&
+		o.Background == defaults.Background
+}
+
+// CacheKey identifies the image for content rendered with these options
+func (o Options) CacheKey(content string) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%s|%d|%s|%d|%x|%x",
+		content, o.Format, o.Size, o.Level, o.Margin, o.Foreground, o.Background))
+	return "qr:" + hex.EncodeToString(sum[:16])
+}
+
+// Render encodes content as a QR code image
+func Render(content string, opts Options) ([]byte, error) {
+	code, err := qrcode.New(content, levels[opts.Level])
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	// We draw the quiet zone ourselves so the margin is configurable
+	code.DisableBorder = true
+	bitmap := withMargin(code.Bitmap(), opts.Margin)
+
+	if opts.Format == FormatSVG {
+		return renderSVG(bitmap, opts), nil
+	}
+	return renderPNG(bitmap, opts)
+}
+
+func withMargin(bitmap [][]bool, margin int) [][]bool {
+	size := len(bitmap) + 2*margin
+	padded := make([][]bool, size)
+	for y := range padded {
+		padded[y] = make([]bool, size)
+		if y >= margin && y < margin+len(bitmap) {
+			copy(padded[y][margin:], bitmap[y-margin])
+		}
+	}
+	return padded
+}
+
+func renderPNG(bitmap [][]bool, opts Options) ([]byte, error) {
+	modules := len(bitmap)
+	// Scale up to a whole number of pixels per module, at least opts.Size
+	scale := max((opts.Size+modules-1)/modules, 1)
+	size := modules * scale
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{opts.Background, opts.Foreground})
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if bitmap[y/scale][x/scale] {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func renderSVG(bitmap [][]bool, opts Options) []byte {
+	modules := len(bitmap)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`, hexColor(opts.Background))
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, hexColor(opts.Foreground))
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			// Merge horizontal runs of dark modules into one rectangle
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,40 @@
+package qr
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCacheable(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"format=svg&size=512&level=H", true},
+		{"size=1024", true},
+		{"size=300", false},
+		{"fg=1a1a2e", false},
+		{"bg=ffffff", true},
+		{"margin=2", false},
+	}
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		opts, err := ParseOptions(query)
+		if err != nil {
+			t.Fatalf("ParseOptions(%q): %v", tt.query, err)
+		}
+		if got := opts.Cacheable(); got != tt.want {
+			t.Errorf("Cacheable(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseOptionsRejects(t *testing.T) {
+	for _, raw := range []string{"format=gif", "size=10", "size=4096", "level=X", "margin=99", "fg=zzzzzz", "bg=fff"} {
+		query, _ := url.ParseQuery(raw)
+		if _, err := ParseOptions(query); err == nil {
+			t.Errorf("ParseOptions(%q) accepted invalid options", raw)
+		}
+	}
+}
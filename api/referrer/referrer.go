@@ -0,0 +1,166 @@
+// Package referrer reduces Referer headers to the referring domain and the
+// kind of traffic source it is
+package referrer
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+//go:embed sources.txt
+var defaultRules []byte
+
+// Source categories
+const (
+	CategorySearch   = "search"
+	CategorySocial   = "social"
+	CategoryEmail    = "email"
+	CategoryDirect   = "direct"   // No referrer
+	CategoryInternal = "internal" // From the short link's own host
+	CategoryReferral = "referral" // Any other site
+)
+
+// ruleCategories are the categories a rules file may assign
+var ruleCategories = []string{CategorySearch, CategorySocial, CategoryEmail}
+
+// Source is where a click came from
+type Source struct {
+	Domain   string // Referring host without "www.", "" when direct
+	Category string
+}
+
+type rule struct {
+	category string
+	domain   string
+	anyTLD   bool // domain ended in ".*"
+}
+
+// Rules maps referrer domains to source categories. A nil *Rules uses the
+// built-in rules.
+type Rules struct {
+	rules []rule
+}
+
+// Load reads the rules in path, or the built-in rules when path is empty
+func Load(path string) (*Rules, error) {
+	data := defaultRules
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return parse(data)
+}
+
+// parse reads "<category> <domain>" lines, skipping blanks and # comments
+func parse(data []byte) (*Rules, error) {
+	rules := &Rules{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want \"<category> <domain>\", got %q", n, line)
+		}
+		if !slices.Contains(ruleCategories, fields[0]) {
+			return nil, fmt.Errorf("line %d: unknown category %q, must be one of %v", n, fields[0], ruleCategories)
+		}
+
+		r := rule{category: fields[0], domain: fields[1]}
+		if base, ok := strings.CutSuffix(r.domain, ".*"); ok {
+			r.domain, r.anyTLD = base, true
+		}
+		rules.rules = append(rules.rules, r)
+	}
+	return rules, scanner.Err()
+}
+
+var builtin, _ = parse(defaultRules)
+
+// Classify reduces a Referer header to its domain and category. ownHosts are
+// the hosts the short link is served on; referrers from them are internal.
+func (r *Rules) Classify(rawReferrer string, ownHosts ...string) Source {
+	host := Domain(rawReferrer)
+	if host == "" {
+		return Source{Category: CategoryDirect}
+	}
+
+	for _, own := range ownHosts {
+		if own = normalizeHost(own); own != "" && host == own {
+			return Source{Domain: host, Category: CategoryInternal}
+		}
+	}
+
+	if r == nil {
+		r = builtin
+	}
+	for _, rule := range r.rules {
+		if rule.matches(host) {
+			return Source{Domain: host, Category: rule.category}
+		}
+	}
+	return Source{Domain: host, Category: CategoryReferral}
+}
+
+// Domain returns the host of a referrer URL, lowercased and without port or
+// "www.". App referrers (android-app://com.example.app/) give the package name.
+func Domain(rawReferrer string) string {
+	rawReferrer = strings.TrimSpace(rawReferrer)
+	if rawReferrer == "" {
+		return ""
+	}
+	u, err := url.Parse(rawReferrer)
+	if err != nil || u.Host == "" {
+		// Some clients send a bare host
+		if u, err = url.Parse("//" + rawReferrer); err != nil {
+			return ""
+		}
+	}
+	return normalizeHost(u.Host)
+}
+
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	return strings.TrimPrefix(host, "www.")
+}
+
+// matches reports whether host is the rule's domain or a subdomain of it
+func (r rule) matches(host string) bool {
+	if !r.anyTLD {
+		return host == r.domain || strings.HasSuffix(host, "."+r.domain)
+	}
+
+	// google.* matches google.com, google.co.uk and their subdomains, but
+	// not google.example.com
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if label != r.domain {
+			continue
+		}
+		switch tld := labels[i+1:]; len(tld) {
+		case 1:
+			return true
+		case 2:
+			return len(tld[1]) == 2 && slices.Contains(secondLevels, tld[0])
+		}
+	}
+	return false
+}
+
+// secondLevels are the labels used under country TLDs, as in .co.uk or .com.br
+var secondLevels = []string{"co", "com", "org", "net", "ac", "gov", "edu", "ne", "or"}
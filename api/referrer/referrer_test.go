@@ -0,0 +1,59 @@
+package referrer
+
+import "testing"
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		rule rule
+		host string
+		want bool
+	}{
+		{rule{domain: "bing.com"}, "bing.com", true},
+		{rule{domain: "bing.com"}, "cn.bing.com", true},
+		{rule{domain: "bing.com"}, "notbing.com", false},
+		{rule{domain: "bing.com"}, "bing.com.evil.net", false},
+		{rule{domain: "google", anyTLD: true}, "google.com", true},
+		{rule{domain: "google", anyTLD: true}, "google.de", true},
+		{rule{domain: "google", anyTLD: true}, "google.co.uk", true},
+		{rule{domain: "google", anyTLD: true}, "google.com.br", true},
+		{rule{domain: "google", anyTLD: true}, "news.google.co.jp", true},
+		{rule{domain: "google", anyTLD: true}, "google.example.com", false},
+		{rule{domain: "google", anyTLD: true}, "google.evil.co", false},
+		{rule{domain: "google", anyTLD: true}, "google", false},
+		{rule{domain: "google", anyTLD: true}, "notgoogle.com", false},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.matches(tt.host); got != tt.want {
+			t.Errorf("%+v.matches(%q) = %v, want %v", tt.rule, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		referrer string
+		want     Source
+	}{
+		{"", Source{Category: CategoryDirect}},
+		{"https://www.google.co.uk/search?q=x", Source{Domain: "google.co.uk", Category: CategorySearch}},
+		{"https://mail.google.com/mail/u/0/", Source{Domain: "mail.google.com", Category: CategoryEmail}},
+		{"https://t.co/abc", Source{Domain: "t.co", Category: CategorySocial}},
+		{"android-app://com.google.android.gm/", Source{Domain: "com.google.android.gm", Category: CategoryEmail}},
+		{"https://sho.rt/other", Source{Domain: "sho.rt", Category: CategoryInternal}},
+		{"https://blog.example.com:8443/post", Source{Domain: "blog.example.com", Category: CategoryReferral}},
+		{"example.org", Source{Domain: "example.org", Category: CategoryReferral}},
+	}
+	for _, tt := range tests {
+		if got := (*Rules)(nil).Classify(tt.referrer, "sho.rt:443"); got != tt.want {
+			t.Errorf("Classify(%q) = %+v, want %+v", tt.referrer, got, tt.want)
+		}
+	}
+}
+
+func TestParseRejectsBadLines(t *testing.T) {
+	for _, data := range []string{"search", "search a.com b.com", "video youtube.com"} {
+		if _, err := parse([]byte(data)); err == nil {
+			t.Errorf("parse(%q) succeeded, want an error", data)
+		}
+	}
+}
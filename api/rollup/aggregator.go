@@ -0,0 +1,117 @@
+// Package rollup keeps the hourly and daily click rollups up to date and
+// prunes analytics data past its retention
+package rollup
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"shawty-ur/api/health"
+	"shawty-ur/api/helper"
+	"shawty-ur/config"
+)
+
+const (
+	// pollInterval is how often the aggregator rolls up newly completed hours
+	pollInterval = time.Minute
+	// pruneInterval is how often data past its retention is deleted
+	pruneInterval = time.Hour
+	// chunkHours is how many hours are rolled up per transaction, so a long
+	// backlog is worked through in steps that each commit their watermark
+	chunkHours = 24
+	// lateness is how long after an hour ends it is rolled up, leaving time
+	// for clicks recorded in the background to be written
+	lateness = 5 * time.Minute
+	// pruneBatchSize is how many raw clicks are deleted per statement
+	pruneBatchSize = 10000
+	// staleAfter is how long the aggregator may go without progress before
+	// readiness reports it stale; a chunk of a long backlog can take a while
+	staleAfter = 15 * time.Minute
+)
+
+// Aggregator rolls clicks up into hourly and daily counts and enforces the
+// retention of each tier: raw clicks, hourly rollups and daily rollups
+type Aggregator struct {
+	cfg       config.AnalyticsConfig
+	analytics *helper.AnalyticsStore
+	heartbeat *health.Heartbeat
+}
+
+// NewAggregator creates an aggregator for the clicks stored in db
+func NewAggregator(db *sql.DB, cfg config.AnalyticsConfig) *Aggregator {
+	return &Aggregator{
+		cfg:       cfg,
+		analytics: helper.NewAnalyticsStore(db),
+		heartbeat: health.NewHeartbeat("rollups", staleAfter),
+	}
+}
+
+// Heartbeat tells whether the aggregator is still making progress
+func (a *Aggregator) Heartbeat() *health.Heartbeat {
+	return a.heartbeat
+}
+
+// Run aggregates and prunes until ctx is done
+func (a *Aggregator) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var lastPrune time.Time
+	for {
+		// Catch up on a backlog chunk by chunk, then wait for the next poll
+		for {
+			_, advanced, err := a.analytics.RollupClicks(ctx, chunkHours, lateness)
+			if err == nil {
+				a.heartbeat.Beat()
+			}
+			if err != nil || !advanced {
+				break
+			}
+		}
+
+		if time.Since(lastPrune) >= pruneInterval {
+			a.prune(ctx)
+			lastPrune = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// prune deletes the data of every tier past its retention. A retention of 0
+// keeps the tier forever.
+func (a *Aggregator) prune(ctx context.Context) {
+	now := time.Now()
+
+	if a.cfg.RawRetention > 0 {
+		var total int64
+		for {
+			n, err := a.analytics.PruneClicks(ctx, now.Add(-a.cfg.RawRetention), pruneBatchSize)
+			total += n
+			if err != nil || n < pruneBatchSize {
+				break
+			}
+		}
+		if total > 0 {
+			slog.InfoContext(ctx, "Pruned raw clicks", "count", total, "retention", a.cfg.RawRetention)
+		}
+	}
+
+	if a.cfg.HourlyRetention > 0 {
+		if n, err := a.analytics.PruneHourlyRollups(ctx, now.Add(-a.cfg.HourlyRetention)); err == nil && n > 0 {
+			slog.InfoContext(ctx, "Pruned hourly rollups", "count", n, "retention", a.cfg.HourlyRetention)
+		}
+	}
+
+	if a.cfg.DailyRetention > 0 {
+		if n, err := a.analytics.PruneDailyRollups(ctx, now.Add(-a.cfg.DailyRetention)); err == nil && n > 0 {
+			slog.InfoContext(ctx, "Pruned daily rollups", "count", n, "retention", a.cfg.DailyRetention)
+		}
+	}
+}
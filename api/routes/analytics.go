@@ -0,0 +1,70 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"shawty-ur/api/helper"
+	"shawty-ur/api/middleware"
+	"shawty-ur/api/utils"
+	"shawty-ur/app"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RegisterAnalyticsRoutes registers routes for analytics across many links
+func RegisterAnalyticsRoutes(r chi.Router, application *app.Application) {
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.RequireAuth(application.SessionStore))
+		r.Get("/me/analytics", accountAnalyticsHandler(application))
+	})
+}
+
+// accountAnalyticsHandler reports the clicks of all the caller's links, or
+// of the links on a team's domains with ?team=<id>. It takes the same
+// from, to and interval parameters as the per-link stats.
+func accountAnalyticsHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := middleware.GetUserFromContext(r)
+
+		from, to, err := parseRange(r)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		interval, err := parseInterval(r, from, to)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		var teamID *int64
+		if v := r.URL.Query().Get("team"); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid team id"})
+				return
+			}
+			teamStore := helper.NewTeamStore(application.DbConnector)
+			role, err := teamStore.GetMemberRole(r.Context(), id, session.UserID)
+			if err != nil {
+				utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load team"})
+				return
+			}
+			if role == "" {
+				utils.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "Team not found"})
+				return
+			}
+			teamID = &id
+		}
+
+		analyticsStore := helper.NewAnalyticsStore(application.DbConnector)
+		stats, err := analyticsStore.GetAccountStats(r.Context(), session.UserID, teamID, from, to, interval)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load analytics"})
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, stats)
+	}
+}
@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"net/http"
+
+	"shawty-ur/api/utils"
+	"shawty-ur/app"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RegisterAppLinkRoutes serves the files iOS and Android fetch to verify that
+// an app may open this domain's links (universal links / app links)
+func RegisterAppLinkRoutes(r chi.Router, application *app.Application) {
+	r.Get("/.well-known/apple-app-site-association", appleAppSiteAssociationHandler(application))
+	r.Get("/apple-app-site-association", appleAppSiteAssociationHandler(application))
+	r.Get("/.well-known/assetlinks.json", assetLinksHandler(application))
+}
+
+func appleAppSiteAssociationHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		association, ok := application.AppLinks.For(r.Host)
+		if !ok || len(association.AppleAppIDs) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, association.AppleAppSiteAssociation())
+	}
+}
+
+func assetLinksHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		association, ok := application.AppLinks.For(r.Host)
+		if !ok || len(association.Android) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, association.AssetLinks())
+	}
+}
@@ -26,14 +26,14 @@ func googleLoginHandler(application *app.Application) http.HandlerFunc {
 		// Generate state token for CSRF protection
 		state, err := auth.GenerateStateToken()
 		if err != nil {
-			slog.Error("Failed to generate state token", "error", err)
+			slog.ErrorContext(r.Context(), "Failed to generate state token", "error", err)
 			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to initiate OAuth"})
 			return
 		}
 
 		// Save state to session
 		if err := application.SessionStore.SaveState(w, r, state); err != nil {
-			slog.Error("Failed to save state to session", "error", err)
+			slog.ErrorContext(r.Context(), "Failed to save state to session", "error", err)
 			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to initiate OAuth"})
 			return
 		}
@@ -50,18 +50,17 @@ func googleCallbackHandler(application *app.Application) http.HandlerFunc {
 		// Validate state token
 		state := r.URL.Query().Get("state")
 		valid, err := application.SessionStore.ValidateState(r, state)
-		slog.Info("Google Auth state: ", "state: ", state, "valid: ", valid)
+		slog.DebugContext(r.Context(), "Google Auth state checked", "valid", valid)
 		if err != nil || !valid {
-			slog.Error("Invalid state token", "error", err)
+			slog.ErrorContext(r.Context(), "Invalid state token", "error", err)
 			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid state token"})
 			return
 		}
 
 		// Get authorization code
 		code := r.URL.Query().Get("code")
-		slog.Info("Google Auth Code: ", "code: ", code)
 		if code == "" {
-			slog.Error("No authorization code provided")
+			slog.ErrorContext(r.Context(), "No authorization code provided")
 			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "No authorization code"})
 			return
 		}
@@ -69,7 +68,7 @@ func googleCallbackHandler(application *app.Application) http.HandlerFunc {
 		// Exchange code for user info
 		googleUser, err := application.OAuthConfig.GetGoogleUserInfo(r.Context(), code)
 		if err != nil {
-			slog.Error("Failed to get Google user info", "error", err)
+			slog.ErrorContext(r.Context(), "Failed to get Google user info", "error", err)
 			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to authenticate with Google"})
 			return
 		}
@@ -78,7 +77,7 @@ func googleCallbackHandler(application *app.Application) http.HandlerFunc {
 		userStore := helper.NewUserStore(application.DbConnector)
 		user, err := userStore.FindOrCreateOAuthUser(r.Context(), googleUser)
 		if err != nil {
-			slog.Error("Failed to find or create OAuth user", "error", err)
+			slog.ErrorContext(r.Context(), "Failed to find or create OAuth user", "error", err)
 			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create user"})
 			return
 		}
@@ -92,7 +91,7 @@ func googleCallbackHandler(application *app.Application) http.HandlerFunc {
 		}
 
 		if err := application.SessionStore.SaveSession(w, r, sessionData); err != nil {
-			slog.Error("Failed to save session", "error", err)
+			slog.ErrorContext(r.Context(), "Failed to save session", "error", err)
 			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create session"})
 			return
 		}
@@ -110,7 +109,7 @@ func googleCallbackHandler(application *app.Application) http.HandlerFunc {
 func logoutHandler(application *app.Application) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if err := application.SessionStore.DestroySession(w, r); err != nil {
-			slog.Error("Failed to destroy session", "error", err)
+			slog.ErrorContext(r.Context(), "Failed to destroy session", "error", err)
 			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to logout"})
 			return
 		}
@@ -0,0 +1,271 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"shawty-ur/api/helper"
+	"shawty-ur/api/middleware"
+	"shawty-ur/api/models"
+	"shawty-ur/api/utils"
+	"shawty-ur/api/utils/redisUtil"
+	"shawty-ur/app"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// hostnamePattern accepts fully qualified hostnames such as go.example.com
+var hostnamePattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+// DomainPayload is the request body for adding a branded domain
+type DomainPayload struct {
+	Hostname string `json:"hostname"`
+	TeamID   int64  `json:"team_id"`
+}
+
+// RegisterDomainRoutes registers routes for managing a team's branded domains
+func RegisterDomainRoutes(r chi.Router, application *app.Application) {
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.RequireAuth(application.SessionStore))
+		r.Get("/domains", listDomainsHandler(application))
+		r.Post("/domains", createDomainHandler(application))
+		r.Post("/domains/{id}/verify", verifyDomainHandler(application))
+		r.Delete("/domains/{id}", deleteDomainHandler(application))
+	})
+}
+
+// linkKey is the Redis key of a short code. Codes on the default domain are
+// stored bare, as they always have been; codes on a branded domain are
+// prefixed with its hostname so the same code can exist on several domains.
+func linkKey(domain *models.Domain, code string) string {
+	if domain == nil {
+		return code
+	}
+	return domain.Hostname + "/" + code
+}
+
+// storedLinkKey is the Redis key of a stored link, looking up its domain
+func storedLinkKey(ctx context.Context, app *app.Application, link *models.Link) (string, error) {
+	if link.DomainID == nil {
+		return linkKey(nil, link.ShortCode), nil
+	}
+
+	domainStore := helper.NewDomainStore(app.DbConnector)
+	domain, err := domainStore.GetDomainByID(ctx, *link.DomainID)
+	if err != nil {
+		return "", err
+	}
+	if domain == nil {
+		return "", fmt.Errorf("domain %d of link %d not found", *link.DomainID, link.ID)
+	}
+	return linkKey(domain, link.ShortCode), nil
+}
+
+// domainID returns the ID of domain, or nil for the default domain
+func domainID(domain *models.Domain) *int64 {
+	if domain == nil {
+		return nil
+	}
+	return &domain.ID
+}
+
+// hostDomain returns the verified branded domain a request was made on, or
+// nil for the default domain and any host that isn't a verified domain
+func hostDomain(app *app.Application, req *http.Request) *models.Domain {
+	domainStore := helper.NewDomainStore(app.DbConnector)
+	domain, err := domainStore.GetVerifiedDomain(req.Context(), req.Host)
+	if err != nil {
+		slog.ErrorContext(req.Context(), "Failed to look up request domain, using default", "host", req.Host, "error", err)
+		return nil
+	}
+	return domain
+}
+
+// queryDomain returns the domain named by the ?domain= hostname of a link API
+// request, or nil for the default domain when there is none. found is false
+// when the hostname isn't a registered domain.
+func queryDomain(app *app.Application, r *http.Request) (domain *models.Domain, found bool, err error) {
+	hostname := r.URL.Query().Get("domain")
+	if hostname == "" {
+		return nil, true, nil
+	}
+
+	domainStore := helper.NewDomainStore(app.DbConnector)
+	domain, err = domainStore.GetVerifiedDomain(r.Context(), hostname)
+	return domain, domain != nil, err
+}
+
+// verificationRecord describes the DNS record that proves ownership of a domain
+func verificationRecord(domain *models.Domain) map[string]string {
+	return map[string]string{
+		"type":  "TXT",
+		"name":  helper.DomainVerificationPrefix + "." + domain.Hostname,
+		"value": helper.DomainVerificationValue + domain.VerificationToken,
+	}
+}
+
+// memberDomain loads the domain named by the {id} URL param, writing a 404
+// and returning nil unless the session user is a member of its team. The
+// caller's role in the team is returned alongside.
+func memberDomain(w http.ResponseWriter, r *http.Request, application *app.Application) (*models.Domain, string) {
+	session, _ := middleware.GetUserFromContext(r)
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid domain id"})
+		return nil, ""
+	}
+
+	domainStore := helper.NewDomainStore(application.DbConnector)
+	domain, err := domainStore.GetDomainByID(r.Context(), id)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load domain"})
+		return nil, ""
+	}
+
+	role := ""
+	if domain != nil {
+		teamStore := helper.NewTeamStore(application.DbConnector)
+		if role, err = teamStore.GetMemberRole(r.Context(), domain.TeamID, session.UserID); err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load team"})
+			return nil, ""
+		}
+	}
+	if role == "" {
+		utils.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "Domain not found"})
+		return nil, ""
+	}
+	return domain, role
+}
+
+func listDomainsHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := middleware.GetUserFromContext(r)
+
+		domainStore := helper.NewDomainStore(application.DbConnector)
+		domains, err := domainStore.ListDomainsForUser(r.Context(), session.UserID)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to list domains"})
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"domains": domains})
+	}
+}
+
+// createDomainHandler registers a hostname for a team. The domain can't be
+// used until the returned TXT record is published and verified.
+func createDomainHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := middleware.GetUserFromContext(r)
+
+		payload := new(DomainPayload)
+		if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+			return
+		}
+		hostname := helper.NormalizeHostname(payload.Hostname)
+		if !hostnamePattern.MatchString(hostname) {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid hostname"})
+			return
+		}
+
+		teamStore := helper.NewTeamStore(application.DbConnector)
+		role, err := teamStore.GetMemberRole(r.Context(), payload.TeamID, session.UserID)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load team"})
+			return
+		}
+		if role != models.TeamRoleOwner {
+			utils.WriteJSON(w, http.StatusForbidden, map[string]string{"error": "Only team owners can add domains"})
+			return
+		}
+
+		domainStore := helper.NewDomainStore(application.DbConnector)
+		domain := &models.Domain{TeamID: payload.TeamID, Hostname: hostname}
+		if err := domainStore.CreateDomain(r.Context(), domain); err != nil {
+			if errors.Is(err, helper.ErrDomainTaken) {
+				utils.WriteJSON(w, http.StatusConflict, map[string]string{"error": "Domain is already registered"})
+				return
+			}
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to add domain"})
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusCreated, map[string]interface{}{
+			"domain":       domain,
+			"verification": verificationRecord(domain),
+		})
+	}
+}
+
+// verifyDomainHandler checks the domain's TXT record and enables it on success
+func verifyDomainHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		domain, _ := memberDomain(w, r, application)
+		if domain == nil {
+			return
+		}
+
+		if !domain.Verified() {
+			domainStore := helper.NewDomainStore(application.DbConnector)
+			err := domainStore.VerifyDomain(r.Context(), domain)
+			switch {
+			case errors.Is(err, helper.ErrDomainClaimExpired):
+				utils.WriteJSON(w, http.StatusGone, map[string]string{"error": err.Error()})
+				return
+			case errors.Is(err, helper.ErrDomainTaken):
+				utils.WriteJSON(w, http.StatusConflict, map[string]string{"error": "Domain was verified by another team"})
+				return
+			case err != nil:
+				slog.WarnContext(r.Context(), "Domain verification failed", "hostname", domain.Hostname, "error", err)
+				utils.WriteJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+					"error":        err.Error(),
+					"verification": verificationRecord(domain),
+				})
+				return
+			}
+		}
+
+		utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"domain": domain})
+	}
+}
+
+// deleteDomainHandler removes a domain and every link on it. Only team owners
+// can delete domains.
+func deleteDomainHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		domain, role := memberDomain(w, r, application)
+		if domain == nil {
+			return
+		}
+		if role != models.TeamRoleOwner {
+			utils.WriteJSON(w, http.StatusForbidden, map[string]string{"error": "Only team owners can delete domains"})
+			return
+		}
+
+		domainStore := helper.NewDomainStore(application.DbConnector)
+		if err := domainStore.DeleteDomain(r.Context(), domain.ID); err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete domain"})
+			return
+		}
+
+		// Drop the domain's short codes from Redis too
+		iter := application.RedisClient.Scan(redisUtil.Ctx, 0, linkKey(domain, "*"), 100).Iterator()
+		for iter.Next(redisUtil.Ctx) {
+			application.RedisClient.Del(redisUtil.Ctx, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			slog.WarnContext(r.Context(), "Failed to clear domain links from Redis", "hostname", domain.Hostname, "error", err)
+		}
+
+		slog.InfoContext(r.Context(), "Domain deleted", "id", domain.ID, "hostname", domain.Hostname)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
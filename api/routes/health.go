@@ -1,21 +1,74 @@
 package routes
 
 import (
+	"context"
+	"log/slog"
 	"net/http"
+	"time"
 
+	"shawty-ur/api/health"
+	"shawty-ur/api/helper"
+	"shawty-ur/api/utils"
 	"shawty-ur/app"
+	"shawty-ur/migrations"
 
 	"github.com/go-chi/chi/v5"
 )
 
+// checkTimeout bounds each readiness check, so a hung dependency fails the
+// probe instead of stalling it
+const checkTimeout = 2 * time.Second
+
+// RegisterHealthRoutes registers the liveness and readiness probes at the
+// root. Every host serves them, so readiness only tells the status of each
+// check; the details are on the internal listener.
 func RegisterHealthRoutes(r chi.Router, application *app.Application) {
-	r.Get("/health", healthHandler(application))
+	r.Get("/livez", livezHandler())
+	r.Get("/readyz", readyzHandler(application, false))
+}
+
+// RegisterInternalHealthRoutes registers the probes on the internal
+// listener, where readiness reports why a check fails
+func RegisterInternalHealthRoutes(r chi.Router, application *app.Application) {
+	r.Get("/livez", livezHandler())
+	r.Get("/readyz", readyzHandler(application, true))
 }
 
-func healthHandler(application *app.Application) http.HandlerFunc {
+// livezHandler reports that the process is up and serving requests. It
+// checks no dependencies, so an outage of Postgres or Redis doesn't get
+// every replica restarted.
+func livezHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteJSON(w, http.StatusOK, map[string]string{"status": health.StatusOK})
+	}
+}
 
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
+// readyzHandler reports whether this replica can serve traffic: Postgres and
+// Redis answer, the schema is migrated and the background workers are
+// making progress. It answers 503 with the failing checks otherwise, with
+// their errors, schema versions and worker beats when detailed.
+func readyzHandler(application *app.Application, detailed bool) http.HandlerFunc {
+	schemaStore := helper.NewSchemaStore(application.DbConnector)
+	checks := map[string]health.Check{
+		"postgres":   health.Ping(application.DbConnector.PingContext),
+		"redis":      health.Ping(func(ctx context.Context) error { return application.RedisClient.Ping(ctx).Err() }),
+		"migrations": health.Migrations(schemaStore.Version, migrations.FS),
+	}
+	for _, heartbeat := range application.Workers {
+		checks["worker:"+heartbeat.Name()] = heartbeat.Check
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := health.Evaluate(r.Context(), checkTimeout, checks)
+		status := http.StatusOK
+		if !report.Ready() {
+			status = http.StatusServiceUnavailable
+			slog.WarnContext(r.Context(), "Not ready", "checks", report.Checks)
+		}
+		if !detailed {
+			report = report.Summary()
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		utils.WriteJSON(w, status, report)
 	}
 }
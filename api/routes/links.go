@@ -0,0 +1,479 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"shawty-ur/api/geoip"
+	"shawty-ur/api/helper"
+	"shawty-ur/api/middleware"
+	"shawty-ur/api/models"
+	"shawty-ur/api/privacy"
+	"shawty-ur/api/routing"
+	"shawty-ur/api/utils"
+	"shawty-ur/api/utils/redisUtil"
+	"shawty-ur/api/visitors"
+	"shawty-ur/app"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+
+	defaultStatsRange = 30 * 24 * time.Hour
+	maxSeriesPoints   = 1000
+	maxVariants       = 10
+	maxVariantWeight  = 10000
+)
+
+// LinkUpdatePayload is the request body for editing a link; omitted fields are left as they are
+type LinkUpdatePayload struct {
+	URL   *string   `json:"url"`
+	Tags  *[]string `json:"tags"`
+	Notes *string   `json:"notes"`
+	// "" goes back to the server's privacy mode
+	PrivacyMode *string `json:"privacy_mode"`
+}
+
+// VariantPayload is one entry of the request body for replacing a link's variants
+type VariantPayload struct {
+	Name        string `json:"name"`
+	Destination string `json:"destination"`
+	Weight      int    `json:"weight"`
+}
+
+// RegisterLinkRoutes registers routes for managing the caller's links
+func RegisterLinkRoutes(r chi.Router, application *app.Application) {
+	r.Get("/links/{code}/qr", qrHandler(application, "code"))
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.RequireAuth(application.SessionStore))
+		r.Get("/links", searchLinksHandler(application))
+		r.Get("/links/broken", brokenLinksHandler(application))
+		r.Patch("/links/{code}", updateLinkHandler(application))
+		r.Delete("/links/{code}", deleteLinkHandler(application))
+		r.Get("/links/{code}/stats", linkStatsHandler(application))
+		r.Get("/links/{code}/live", liveClicksHandler(application))
+		r.Get("/links/{code}/preview", previewRoutingHandler(application))
+		r.Get("/links/{code}/variants", listVariantsHandler(application))
+		r.Put("/links/{code}/variants", replaceVariantsHandler(application))
+	})
+}
+
+// ownedLink loads the link named by the {code} URL param, on the branded
+// domain named by ?domain= if any, writing a 404 and returning nil unless it
+// belongs to the session user or to a domain of one of their teams
+func ownedLink(w http.ResponseWriter, r *http.Request, application *app.Application) *models.Link {
+	session, _ := middleware.GetUserFromContext(r)
+
+	domain, found, err := queryDomain(application, r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load link"})
+		return nil
+	}
+	if !found {
+		utils.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "Link not found"})
+		return nil
+	}
+
+	linkStore := helper.NewLinkStore(application.DbConnector)
+	link, err := linkStore.GetLinkByCode(r.Context(), domainID(domain), chi.URLParam(r, "code"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load link"})
+		return nil
+	}
+	if link == nil {
+		utils.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "Link not found"})
+		return nil
+	}
+	if link.UserID != nil && *link.UserID == session.UserID {
+		return link
+	}
+
+	// Links on a branded domain are shared by every member of its team
+	role := ""
+	if domain != nil {
+		teamStore := helper.NewTeamStore(application.DbConnector)
+		if role, err = teamStore.GetMemberRole(r.Context(), domain.TeamID, session.UserID); err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load link"})
+			return nil
+		}
+	}
+	if role == "" {
+		utils.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "Link not found"})
+		return nil
+	}
+	return link
+}
+
+// searchLinksHandler searches the caller's and their teams' links by alias, destination, title, tags and notes
+func searchLinksHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := middleware.GetUserFromContext(r)
+		page, pageSize := parsePagination(r)
+
+		linkStore := helper.NewLinkStore(application.DbConnector)
+		links, total, err := linkStore.SearchLinks(r.Context(), session.UserID, r.URL.Query().Get("q"), pageSize, (page-1)*pageSize)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to search links", "error", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to search links"})
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"links":     links,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		})
+	}
+}
+
+// brokenLinksHandler lists the caller's links whose destinations keep failing health checks
+func brokenLinksHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := middleware.GetUserFromContext(r)
+
+		linkStore := helper.NewLinkStore(application.DbConnector)
+		links, err := linkStore.ListBrokenLinks(r.Context(), session.UserID)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to list broken links"})
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"links": links})
+	}
+}
+
+// updateLinkHandler edits the destination, tags or notes of a link
+func updateLinkHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := ownedLink(w, r, application)
+		if link == nil {
+			return
+		}
+
+		payload := new(LinkUpdatePayload)
+		if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+			return
+		}
+		urlChanged := payload.URL != nil && *payload.URL != link.OriginalURL
+		if urlChanged {
+			if !regexp.MustCompile(pattern).MatchString(*payload.URL) {
+				utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid Request Url"})
+				return
+			}
+			link.OriginalURL = *payload.URL
+		}
+		if payload.Tags != nil {
+			link.Tags = *payload.Tags
+		}
+		if payload.Notes != nil {
+			link.Notes = payload.Notes
+			if *payload.Notes == "" {
+				link.Notes = nil
+			}
+		}
+		if payload.PrivacyMode != nil {
+			if *payload.PrivacyMode != "" && !privacy.ValidMode(*payload.PrivacyMode) {
+				utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("privacy_mode must be one of %v", privacy.Modes)})
+				return
+			}
+			link.PrivacyMode = payload.PrivacyMode
+			if *payload.PrivacyMode == "" {
+				link.PrivacyMode = nil
+			}
+		}
+
+		linkStore := helper.NewLinkStore(application.DbConnector)
+		if err := linkStore.UpdateLink(r.Context(), link); err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update link"})
+			return
+		}
+
+		// Resolve reads the destination from Redis; keep the link's expiry as it is
+		if urlChanged {
+			key, err := storedLinkKey(r.Context(), application, link)
+			if err == nil {
+				err = application.RedisClient.SetArgs(redisUtil.Ctx, key, link.OriginalURL, redis.SetArgs{Mode: "XX", KeepTTL: true}).Err()
+			}
+			if err != nil && err != redis.Nil {
+				slog.ErrorContext(r.Context(), "Failed to update link destination in Redis", "short_code", link.ShortCode, "error", err)
+				utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update link"})
+				return
+			}
+		}
+
+		publishLinkEvent(application, models.EventLinkUpdated, link)
+		utils.WriteJSON(w, http.StatusOK, link)
+	}
+}
+
+// deleteLinkHandler removes a link, its variants and its click history
+func deleteLinkHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := ownedLink(w, r, application)
+		if link == nil {
+			return
+		}
+
+		key, err := storedLinkKey(r.Context(), application, link)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete link"})
+			return
+		}
+		if err := application.RedisClient.Del(redisUtil.Ctx, key).Err(); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to delete link from Redis", "short_code", link.ShortCode, "error", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete link"})
+			return
+		}
+
+		linkStore := helper.NewLinkStore(application.DbConnector)
+		if err := linkStore.DeleteLink(r.Context(), link.ID); err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete link"})
+			return
+		}
+
+		slog.InfoContext(r.Context(), "Link deleted", "id", link.ID, "short_code", link.ShortCode)
+		publishLinkEvent(application, models.EventLinkDeleted, link)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// parsePagination reads ?page= and ?page_size= falling back to sane defaults
+func parsePagination(r *http.Request) (page, pageSize int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err = strconv.Atoi(r.URL.Query().Get("page_size"))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return page, pageSize
+}
+
+// linkStatsHandler reports the clicks of a link, in total, over time and per
+// A/B variant
+func linkStatsHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := ownedLink(w, r, application)
+		if link == nil {
+			return
+		}
+
+		from, to, err := parseRange(r)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		interval, err := parseInterval(r, from, to)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		analyticsStore := helper.NewAnalyticsStore(application.DbConnector)
+		stats, err := analyticsStore.GetLinkStats(r.Context(), link, from, to, interval)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load link stats"})
+			return
+		}
+
+		stats.UniqueVisitors, err = visitors.Count(r.Context(), application.RedisClient, link.ID, from, to)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to count unique visitors", "short_code", link.ShortCode, "error", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load link stats"})
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, stats)
+	}
+}
+
+// previewRoutingHandler shows where a simulated visit would be redirected by
+// the link's routing rules. The visit defaults to now, from the caller's own
+// user agent, and can be overridden with ?at= (RFC 3339), ?user_agent=,
+// ?country= and ?region=.
+func previewRoutingHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := ownedLink(w, r, application)
+		if link == nil {
+			return
+		}
+
+		query := r.URL.Query()
+		sim := r.Clone(r.Context())
+		if ua := query.Get("user_agent"); ua != "" {
+			sim.Header.Set("User-Agent", ua)
+		}
+		visitor := routing.VisitorFromRequest(sim, geoip.Location{
+			Country: strings.ToUpper(query.Get("country")),
+			Region:  strings.ToUpper(query.Get("region")),
+		})
+		if at := query.Get("at"); at != "" {
+			t, err := time.Parse(time.RFC3339, at)
+			if err != nil {
+				utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid at: " + err.Error()})
+				return
+			}
+			visitor.Time = t
+		}
+
+		response := map[string]interface{}{
+			"visitor":      visitor,
+			"matched_rule": nil,
+			"destination":  link.OriginalURL,
+		}
+		if i := link.RoutingRules.MatchIndex(visitor); i >= 0 {
+			response["matched_rule"] = map[string]interface{}{"index": i, "rule": link.RoutingRules[i]}
+			response["destination"] = link.RoutingRules[i].Destination
+		}
+
+		utils.WriteJSON(w, http.StatusOK, response)
+	}
+}
+
+func listVariantsHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := ownedLink(w, r, application)
+		if link == nil {
+			return
+		}
+
+		variantStore := helper.NewVariantStore(application.DbConnector)
+		variants, err := variantStore.ListVariants(r.Context(), link.ID)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to list variants"})
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"variants": variants})
+	}
+}
+
+// replaceVariantsHandler sets the weighted destinations of a split-test link.
+// Sending an empty list turns the split test off.
+func replaceVariantsHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := ownedLink(w, r, application)
+		if link == nil {
+			return
+		}
+
+		var payload []VariantPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+			return
+		}
+		variants, err := validateVariants(payload)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		variantStore := helper.NewVariantStore(application.DbConnector)
+		if err := variantStore.ReplaceVariants(r.Context(), link.ID, variants); err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to save variants"})
+			return
+		}
+
+		slog.InfoContext(r.Context(), "Link variants updated", "short_code", link.ShortCode, "count", len(variants))
+		publishLinkEvent(application, models.EventLinkUpdated, link)
+		utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"variants": variants})
+	}
+}
+
+func validateVariants(payload []VariantPayload) ([]*models.LinkVariant, error) {
+	if len(payload) > maxVariants {
+		return nil, fmt.Errorf("at most %d variants are allowed", maxVariants)
+	}
+
+	seen := make(map[string]bool, len(payload))
+	variants := make([]*models.LinkVariant, 0, len(payload))
+	for i, p := range payload {
+		name := strings.TrimSpace(p.Name)
+		if name == "" || seen[name] {
+			return nil, fmt.Errorf("variant %d: name must be set and unique", i+1)
+		}
+		seen[name] = true
+
+		u, err := url.Parse(p.Destination)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("variant %d: destination must be an absolute http(s) url", i+1)
+		}
+		if p.Weight < 0 || p.Weight > maxVariantWeight {
+			return nil, fmt.Errorf("variant %d: weight must be between 0 and %d", i+1, maxVariantWeight)
+		}
+
+		variants = append(variants, &models.LinkVariant{
+			Name:        name,
+			Destination: p.Destination,
+			Weight:      p.Weight,
+		})
+	}
+	return variants, nil
+}
+
+// parseRange reads the ?from= and ?to= RFC 3339 timestamps of a stats
+// request, defaulting to the last 30 days
+func parseRange(r *http.Request) (from, to time.Time, err error) {
+	to = time.Now()
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+
+	from = to.Add(-defaultStatsRange)
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			return from, to, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("from must be before to")
+	}
+	return from, to, nil
+}
+
+// parseInterval reads the ?interval= bucket size of a stats time series,
+// "hour" or "day". It defaults to hours for ranges of up to two days.
+func parseInterval(r *http.Request, from, to time.Time) (string, error) {
+	interval := r.URL.Query().Get("interval")
+	switch interval {
+	case "":
+		interval = helper.IntervalDay
+		if to.Sub(from) <= 48*time.Hour {
+			interval = helper.IntervalHour
+		}
+	case helper.IntervalHour, helper.IntervalDay:
+	default:
+		return "", fmt.Errorf("interval must be %q or %q", helper.IntervalHour, helper.IntervalDay)
+	}
+
+	step := time.Hour
+	if interval == helper.IntervalDay {
+		step = 24 * time.Hour
+	}
+	if to.Sub(from)/step > maxSeriesPoints {
+		return "", fmt.Errorf("range is too long for interval %q, at most %d buckets", interval, maxSeriesPoints)
+	}
+	return interval, nil
+}
@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"shawty-ur/api/live"
+	"shawty-ur/app"
+)
+
+// liveKeepAlive is how often a comment is sent on an idle stream so proxies
+// don't close it
+const liveKeepAlive = 15 * time.Second
+
+// liveClicksHandler streams the clicks of a link as Server-Sent Events until
+// the client disconnects. Each click is an event named "click" whose data is
+// a JSON live.Event.
+func liveClicksHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := ownedLink(w, r, application)
+		if link == nil {
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		ctx := r.Context()
+		sub := application.RedisClient.Subscribe(ctx, live.Channel(link.ID))
+		defer sub.Close()
+		if _, err := sub.Receive(ctx); err != nil {
+			slog.ErrorContext(ctx, "Failed to subscribe to live clicks", "short_code", link.ShortCode, "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		messages := sub.Channel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no") // Stop nginx from buffering the stream
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "retry: 3000\n\n")
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(liveKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				fmt.Fprintf(w, "event: click\ndata: %s\n\n", msg.Payload)
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			}
+			flusher.Flush()
+		}
+	}
+}
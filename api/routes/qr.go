@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"shawty-ur/api/metrics"
+	"shawty-ur/api/qr"
+	"shawty-ur/api/utils"
+	"shawty-ur/api/utils/redisUtil"
+	"shawty-ur/app"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// qrMarkerParam is added to the URL encoded in QR codes so scans can be told
+// apart from other clicks. Resolve strips it before redirecting.
+const qrMarkerParam = "qr"
+
+// qrCacheTTL is how long rendered QR images are kept in Redis
+const qrCacheTTL = 24 * time.Hour
+
+// qrHandler renders the QR code of the short link named by the urlParam route
+// param. The link's domain is the request's host on the redirect routes, and
+// the ?domain= hostname on the API route.
+func qrHandler(app *app.Application, urlParam string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		hash := chi.URLParam(req, urlParam)
+
+		opts, err := qr.ParseOptions(req.URL.Query())
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		domain, found := hostDomain(app, req), true
+		if urlParam == "code" {
+			if domain, found, err = queryDomain(app, req); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+		}
+		if !found {
+			http.Error(w, "Short URL not found or expired", http.StatusNotFound)
+			return
+		}
+
+		exists, err := app.RedisClient.Exists(redisUtil.Ctx, linkKey(domain, hash)).Result()
+		if err != nil {
+			slog.ErrorContext(req.Context(), "Redis error while looking up QR code link", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if exists == 0 {
+			http.Error(w, "Short URL not found or expired", http.StatusNotFound)
+			return
+		}
+
+		content := shortURL(domain, hash) + "?" + qrMarkerParam + "=1"
+		cacheKey := opts.CacheKey(content)
+		cacheable := opts.Cacheable()
+
+		var image []byte
+		if cacheable {
+			if image, err = app.RedisClient.Get(redisUtil.Ctx, cacheKey).Bytes(); err == nil {
+				metrics.CacheHits.WithLabelValues(metrics.CacheQR).Inc()
+			} else {
+				metrics.CacheMisses.WithLabelValues(metrics.CacheQR).Inc()
+			}
+		}
+		if image == nil {
+			image, err = qr.Render(content, opts)
+			if err != nil {
+				slog.ErrorContext(req.Context(), "Failed to render QR code", "hash", hash, "error", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if cacheable {
+				if err := app.RedisClient.Set(redisUtil.Ctx, cacheKey, image, qrCacheTTL).Err(); err != nil {
+					slog.WarnContext(req.Context(), "Failed to cache QR code", "hash", hash, "error", err)
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", opts.ContentType())
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.WriteHeader(http.StatusOK)
+		w.Write(image)
+	}
+}
@@ -1,12 +1,31 @@
 package routes
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"os"
+	"shawty-ur/api/bots"
+	"shawty-ur/api/deeplink"
+	"shawty-ur/api/helper"
+	"shawty-ur/api/live"
+	"shawty-ur/api/logging"
+	"shawty-ur/api/metrics"
+	"shawty-ur/api/models"
+	"shawty-ur/api/privacy"
+	"shawty-ur/api/referrer"
+	"shawty-ur/api/routing"
+	"shawty-ur/api/tracing"
+	"shawty-ur/api/useragent"
+	"shawty-ur/api/utils"
 	"shawty-ur/api/utils/redisUtil"
+	"shawty-ur/api/visitors"
+	"shawty-ur/api/webhook"
 	"shawty-ur/app"
 	"shawty-ur/config"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/redis/go-redis/v9"
@@ -15,26 +34,55 @@ import (
 func RegisterResolveRoutes(r chi.Router, app *app.Application) {
 	slog.Info("RegisterResolveRoutes called - registering /{url} route")
 	r.Get("/{url}", Resolve(app))
+	r.Get("/{url}/qr", resolveQR(app))
+	// Path suffixes (/abc123/extra/path) for links with forward_path enabled
+	r.Get("/{url}/*", Resolve(app))
 	slog.Info("RegisterResolveRoutes completed")
 }
 
+// resolveQR serves the QR code of /{url}/qr, except for links with
+// forward_path, where /qr is a path suffix like any other
+func resolveQR(app *app.Application) http.HandlerFunc {
+	qrCode, resolve := qrHandler(app, "url"), Resolve(app)
+	return func(w http.ResponseWriter, req *http.Request) {
+		link := lookupLink(app, req, hostDomain(app, req), chi.URLParam(req, "url"))
+		if link != nil && link.ForwardPath {
+			resolve(w, req)
+			return
+		}
+		qrCode(w, req)
+	}
+}
+
 func Resolve(app *app.Application) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		// Extract the short URL hash from the path
 		hash := chi.URLParam(req, "url")
-		slog.Info("Resolving short URL", "hash", hash)
+		slog.InfoContext(req.Context(), "Resolving short URL", "hash", hash)
+
+		// Branded domains have their own namespace of short codes
+		domain := hostDomain(app, req)
+		key := linkKey(domain, hash)
 
 		// Look up the original URL in Redis (DB 0 - where shorten() saves URLs)
-		value, err := app.RedisClient.Get(redisUtil.Ctx, hash).Result()
+		value, err := app.RedisClient.Get(req.Context(), key).Result()
 		if err == redis.Nil {
-			slog.Warn("Short URL not found", "hash", hash)
+			metrics.CacheMisses.WithLabelValues(metrics.CacheLinks).Inc()
+			slog.WarnContext(req.Context(), "Short URL not found", "hash", hash)
 			http.Error(w, "Short URL not found or expired", http.StatusNotFound)
 			return // ✅ MUST RETURN HERE!
 		} else if err != nil {
-			slog.Error("Redis error while resolving URL", "error", err)
+			slog.ErrorContext(req.Context(), "Redis error while resolving URL", "error", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return // ✅ MUST RETURN HERE!
 		}
+		metrics.CacheHits.WithLabelValues(metrics.CacheLinks).Inc()
+
+		link := lookupLink(app, req, domain, hash)
+		if chi.URLParam(req, "*") != "" && (link == nil || !link.ForwardPath) {
+			http.Error(w, "Short URL not found or expired", http.StatusNotFound)
+			return
+		}
 
 		// Connect to Redis DB 1 for analytics/counter
 		rInr, err := redisUtil.New(config.RedisConfig{
@@ -43,17 +91,260 @@ func Resolve(app *app.Application) http.HandlerFunc {
 			DB:       1,
 		})
 		if err != nil {
-			slog.Error("Failed to connect to Redis DB 1 for analytics", "error", err)
+			slog.ErrorContext(req.Context(), "Failed to connect to Redis DB 1 for analytics", "error", err)
 			// Don't fail the redirect just because analytics failed
 			// Just log and continue
 		} else {
 			defer rInr.Close()
 			// Increment click counter for this URL
-			_ = rInr.Incr(redisUtil.Ctx, hash)
+			_ = rInr.Incr(req.Context(), key)
+		}
+
+		viaQR := stripQRMarker(req)
+		ip := utils.ClientIP(req)
+		loc := app.GeoIP.Lookup(ip)
+		dynamic := false
+		if link != nil {
+			click := &models.Click{
+				URLID:     link.ID,
+				IPAddress: ip,
+				UserAgent: req.UserAgent(),
+				Referrer:  req.Referer(),
+				Country:   loc.Country,
+				City:      loc.City,
+				ViaQR:     viaQR,
+				UTM:       utmFromQuery(req),
+				ClickedAt: time.Now(),
+			}
+			source := app.Referrers.Classify(req.Referer(), req.Host, referrer.Domain(os.Getenv("DOMAIN")))
+			click.ReferrerDomain, click.ReferrerCategory = source.Domain, source.Category
+
+			if destination, ok := link.RoutingRules.Match(routing.VisitorFromRequest(req, loc)); ok {
+				value = destination
+				dynamic = true
+			} else if variant := assignVariant(app, w, req, link); variant != nil {
+				value = variant.Destination
+				click.VariantID = &variant.ID
+				dynamic = true
+			}
+
+			value, err = passthroughDestination(req, link, value)
+			if err != nil {
+				slog.WarnContext(req.Context(), "Rejected passthrough request", "hash", hash, "error", err)
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+			// Visitors opting out of tracking may not be recorded at all
+			optedOut := privacy.OptedOut(req)
+			if !optedOut || app.Config.Privacy.OptOut != privacy.OptOutSkip {
+				go recordClick(context.WithoutCancel(req.Context()), app, link, click, bots.SignalsFromRequest(req, ip), optedOut)
+			}
+
+			// Mobile visitors get a bridge page that tries the app first
+			if appURL := link.DeepLink.AppURL(useragent.Parse(req.UserAgent())); appURL != "" {
+				slog.InfoContext(req.Context(), "Serving deep link bridge", "hash", hash,
+					"app_host", logging.URLHost(appURL), "fallback_host", logging.URLHost(value))
+				metrics.UrlsResolvedTotal.Inc()
+				if err := deeplink.WriteBridge(w, appURL, value); err != nil {
+					slog.ErrorContext(req.Context(), "Failed to write deep link bridge", "error", err)
+				}
+				return
+			}
 		}
 
 		// Redirect to the original URL
-		slog.Info("Redirecting to original URL", "hash", hash, "url", value)
-		http.Redirect(w, req, value, http.StatusMovedPermanently)
+		slog.InfoContext(req.Context(), "Redirecting to original URL", "hash", hash, "host", logging.URLHost(value))
+		metrics.UrlsResolvedTotal.Inc()
+		// Browsers cache 301s, so visitor-dependent destinations get a 302
+		status := http.StatusMovedPermanently
+		if dynamic {
+			status = http.StatusFound
+		}
+		http.Redirect(w, req, value, status)
+	}
+}
+
+// variantCookiePrefix names the cookie that pins a visitor to a variant of a split-test link
+const variantCookiePrefix = "shawty_v_"
+
+// assignVariant picks the A/B variant a visitor is sent to. The choice is
+// remembered in a cookie so returning visitors see the same variant, as long
+// as it still exists and has a non-zero weight.
+func assignVariant(app *app.Application, w http.ResponseWriter, req *http.Request, link *models.Link) *models.LinkVariant {
+	if !link.HasVariants {
+		return nil
+	}
+	variantStore := helper.NewVariantStore(app.DbConnector)
+	variants, err := variantStore.ListVariants(req.Context(), link.ID)
+	if err != nil || len(variants) == 0 {
+		return nil
+	}
+
+	cookieName := variantCookiePrefix + link.ShortCode
+	if cookie, err := req.Cookie(cookieName); err == nil {
+		if id, err := strconv.ParseInt(cookie.Value, 10, 64); err == nil {
+			for _, variant := range variants {
+				if variant.ID == id && variant.Weight > 0 {
+					return variant
+				}
+			}
+		}
+	}
+
+	variant := helper.PickVariant(variants)
+	if variant == nil {
+		return nil
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    strconv.FormatInt(variant.ID, 10),
+		Path:     "/" + link.ShortCode,
+		MaxAge:   90 * 24 * 60 * 60, // 90 days
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return variant
+}
+
+// lookupLink loads the stored options of a short link. Links that only exist
+// in Redis, or that can't be loaded right now, resolve with default options.
+func lookupLink(app *app.Application, req *http.Request, domain *models.Domain, hash string) *models.Link {
+	linkStore := helper.NewLinkStore(app.DbConnector)
+	link, err := linkStore.GetLinkByCode(req.Context(), domainID(domain), hash)
+	if err != nil {
+		slog.ErrorContext(req.Context(), "Failed to load link options, using defaults", "hash", hash, "error", err)
+		return nil
+	}
+	return link
+}
+
+// stripQRMarker removes the QR scan marker from the request's query string,
+// so it isn't forwarded to the destination, and reports whether it was there
+func stripQRMarker(req *http.Request) bool {
+	query := req.URL.Query()
+	if query.Get(qrMarkerParam) != "1" {
+		return false
+	}
+
+	var params []string
+	for _, param := range strings.Split(req.URL.RawQuery, "&") {
+		if param != qrMarkerParam+"=1" {
+			params = append(params, param)
+		}
+	}
+	req.URL.RawQuery = strings.Join(params, "&")
+	return true
+}
+
+// utmFromQuery captures the campaign parameters the short link was visited with
+func utmFromQuery(req *http.Request) models.UTMParams {
+	query := req.URL.Query()
+	return models.UTMParams{
+		Source:   query.Get("utm_source"),
+		Medium:   query.Get("utm_medium"),
+		Campaign: query.Get("utm_campaign"),
+		Term:     query.Get("utm_term"),
+		Content:  query.Get("utm_content"),
+	}
+}
+
+// recordClick stores a click in the background so it never delays the
+// redirect, sends it to live streams and queues it as a link.clicked webhook
+// event. Bot clicks are tagged, and left out of unique visitor counts, and
+// the user agent is parsed into browser, OS and device columns. The IP
+// address is anonymized per the privacy mode before it leaves this function.
+// ctx carries the trace of the redirect, without its cancellation.
+func recordClick(ctx context.Context, app *app.Application, link *models.Link, click *models.Click, signals bots.Signals, optedOut bool) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	ctx, span := tracing.Tracer.Start(ctx, "record click")
+	defer span.End()
+
+	click.BotReason = app.Bots.Classify(ctx, signals)
+	click.IsBot = click.BotReason != ""
+
+	ua := useragent.Parse(click.UserAgent)
+	click.Browser, click.BrowserVersion = ua.Browser, ua.BrowserVersion
+	click.OS, click.OSVersion = ua.OS, ua.OSVersion
+	click.Device = ua.Device
+
+	minimize := optedOut && app.Config.Privacy.OptOut == privacy.OptOutMinimize
+
+	if !click.IsBot && !minimize {
+		if err := visitors.Record(ctx, app.RedisClient, link.ID, click.IPAddress, click.UserAgent, click.ClickedAt); err != nil {
+			slog.WarnContext(ctx, "Failed to count unique visitor", "short_code", link.ShortCode, "error", err)
+		}
+	}
+
+	if minimize {
+		minimizeClick(click)
+	} else {
+		anonymizeClick(ctx, app, link, click)
+	}
+
+	if err := live.Publish(ctx, app.RedisClient, link.ID, live.NewEvent(click)); err != nil {
+		slog.WarnContext(ctx, "Failed to publish live click", "short_code", link.ShortCode, "error", err)
+	}
+
+	analyticsStore := helper.NewAnalyticsStore(app.DbConnector)
+	if err := analyticsStore.RecordClick(ctx, click); err != nil {
+		return
+	}
+	if webhook.WantsClicks(ctx, app.DbConnector, app.RedisClient, link.UserID) {
+		webhook.Publish(ctx, app.DbConnector, link.UserID, models.EventLinkClicked, map[string]any{
+			"link_id":    link.ID,
+			"short_code": link.ShortCode,
+			"click":      webhook.NewClick(click),
+		})
+	}
+}
+
+// anonymizeClick truncates or hashes the click's IP address, following the
+// stricter of the server and link privacy modes
+func anonymizeClick(ctx context.Context, app *app.Application, link *models.Link, click *models.Click) {
+	mode := app.Config.Privacy.Mode
+	if link.PrivacyMode != nil {
+		mode = privacy.Strictest(mode, *link.PrivacyMode)
+	}
+
+	switch mode {
+	case privacy.ModeTruncate:
+		click.IPAddress = privacy.Truncate(click.IPAddress)
+	case privacy.ModeHash:
+		hash, err := privacy.Hash(ctx, app.RedisClient, click.IPAddress, click.ClickedAt)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to hash visitor IP, dropping it", "short_code", link.ShortCode, "error", err)
+		}
+		click.IPAddress, click.IPHash = "", hash
+	}
+}
+
+// minimizeClick keeps only what a click is counted by, for visitors who sent
+// DNT or Sec-GPC: nothing that could identify them, like their IP address,
+// full user agent, referrer URL or city, or tell their visits apart
+func minimizeClick(click *models.Click) {
+	click.IPAddress = ""
+	click.UserAgent = ""
+	click.Referrer = ""
+	click.City = ""
+	click.BrowserVersion = ""
+	click.OSVersion = ""
+}
+
+// passthroughDestination appends the request's path suffix and query string
+// to destination, as enabled on the link
+func passthroughDestination(req *http.Request, link *models.Link, destination string) (string, error) {
+	var pathSuffix, rawQuery string
+	if link.ForwardPath {
+		// Work on the escaped path so encoded slashes and the like survive as-is
+		escaped := strings.TrimPrefix(req.URL.EscapedPath(), "/")
+		if _, suffix, found := strings.Cut(escaped, "/"); found {
+			pathSuffix = suffix
+		}
+	}
+	if link.ForwardQuery {
+		rawQuery = req.URL.RawQuery
 	}
+	return helper.AppendPassthrough(destination, pathSuffix, rawQuery)
 }
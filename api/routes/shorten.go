@@ -1,14 +1,22 @@
 package routes
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"regexp"
+	"shawty-ur/api/deeplink"
+	"shawty-ur/api/helper"
+	"shawty-ur/api/metrics"
+	"shawty-ur/api/models"
+	"shawty-ur/api/privacy"
+	"shawty-ur/api/routing"
+	"shawty-ur/api/utils"
 	"shawty-ur/api/utils/redisUtil"
 	"shawty-ur/app"
 	"shawty-ur/config"
@@ -25,6 +33,23 @@ type Request struct {
 	URL         string        `json:"url"`
 	CustomShort string        `json:"custom_short"`
 	Expiry      time.Duration `json:"expiry"`
+	Tags        []string      `json:"tags"`
+	Notes       string        `json:"notes"`
+	// UTM parameters merged into the destination query string. Fields left
+	// empty are filled in from UTMPreset, the name of a saved preset.
+	UTM       models.UTMParams `json:"utm"`
+	UTMPreset string           `json:"utm_preset"`
+	// Forward the visitor's query string / trailing path to the destination on redirect
+	ForwardQuery bool `json:"forward_query"`
+	ForwardPath  bool `json:"forward_path"`
+	// Conditional redirects by device/OS, with URL as the fallback destination
+	RoutingRules routing.Rules `json:"routing_rules"`
+	// App URLs to try on iOS/Android before falling back to the web destination
+	DeepLink *deeplink.Config `json:"deep_link"`
+	// Verified branded hostname of one of the caller's teams to create the link on
+	Domain string `json:"domain"`
+	// Anonymize visitor IPs more strictly than the server does: "truncate" or "hash"
+	PrivacyMode string `json:"privacy_mode"`
 }
 
 type Response struct {
@@ -35,6 +60,20 @@ type Response struct {
 
 var pattern string = "^(https?://)?([a-zA-Z0-9-]+\\.)+[a-zA-Z]{2,}(:\\d+)?(/.*)?$"
 
+// customShortPattern is what a custom_short alias may look like
+var customShortPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,50}$`)
+
+// reservedCodes can't be used as custom aliases since they are routes of their own
+var reservedCodes = map[string]bool{
+	"api":    true,
+	"health": true,
+	"livez":  true,
+	"readyz": true,
+	// App association files, see RegisterAppLinkRoutes
+	".well-known":                true,
+	"apple-app-site-association": true,
+}
+
 func RegisterServiceRoutes(r chi.Router, app *app.Application) {
 	// r.Get("/resolve", resolve(app))
 	r.Post("/shorten", shorten(app))
@@ -42,7 +81,6 @@ func RegisterServiceRoutes(r chi.Router, app *app.Application) {
 
 func shorten(app *app.Application) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		fmt.Printf("holaaa \n\n\n")
 		request := new(Request)
 		err := json.NewDecoder(req.Body).Decode(&request)
 		if err != nil {
@@ -55,7 +93,7 @@ func shorten(app *app.Application) http.HandlerFunc {
 			DB:       1,
 		})
 		if err != nil {
-			slog.Info("INTERNAL SERVER ERROR ON REDIS !!")
+			slog.InfoContext(req.Context(), "INTERNAL SERVER ERROR ON REDIS !!")
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		}
 		ip, _, err := net.SplitHostPort(req.RemoteAddr)
@@ -65,7 +103,7 @@ func shorten(app *app.Application) http.HandlerFunc {
 			return
 		}
 
-		fmt.Println("Client IP:", ip)
+		slog.DebugContext(req.Context(), "Shortening URL", "client_ip", privacy.AnonymizeAddr(ip, app.Config.Privacy.Mode))
 
 		value, err := r2.Get(redisUtil.Ctx, ip).Result()
 		if err == redis.Nil {
@@ -83,10 +121,41 @@ func shorten(app *app.Application) http.HandlerFunc {
 				json.NewEncoder(w).Encode(response)
 			}
 		}
+		if err := applyRequestUTM(app, req, request); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		if err := request.RoutingRules.Validate(); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if err := request.DeepLink.Validate(); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if request.PrivacyMode != "" && !privacy.ValidMode(request.PrivacyMode) {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("privacy_mode must be one of %v", privacy.Modes)})
+			return
+		}
+		if request.CustomShort != "" && (!customShortPattern.MatchString(request.CustomShort) || reservedCodes[strings.ToLower(request.CustomShort)]) {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "custom_short must be 3-50 letters, digits, '-' or '_' and not a reserved word"})
+			return
+		}
+
+		domain, status, err := requestedDomain(app, req, request)
+		if err != nil {
+			utils.WriteJSON(w, status, map[string]string{"error": err.Error()})
+			return
+		}
+
 		regex := regexp.MustCompile(pattern)
 		if regex.MatchString(request.URL) {
-			hash := uuid.New().String()
-			hash = strings.ReplaceAll(hash, "-", "")[:8]
+			hash := request.CustomShort
+			if hash == "" {
+				hash = uuid.New().String()
+				hash = strings.ReplaceAll(hash, "-", "")[:8]
+			}
 
 			r1, err := redisUtil.New(config.RedisConfig{
 				Addr:     os.Getenv("REDIS_ADDR"),
@@ -94,19 +163,40 @@ func shorten(app *app.Application) http.HandlerFunc {
 				DB:       0,
 			})
 			if err != nil {
-				slog.Info("INTERNAL SERVER ERROR ON REDIS !!")
+				slog.InfoContext(req.Context(), "INTERNAL SERVER ERROR ON REDIS !!")
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+			// Links created before they were stored in Postgres only exist in Redis
+			if request.CustomShort != "" {
+				if n, _ := r1.Exists(redisUtil.Ctx, linkKey(domain, hash)).Result(); n > 0 {
+					utils.WriteJSON(w, http.StatusConflict, map[string]string{"error": helper.ErrShortCodeTaken.Error()})
+					return
+				}
+			}
+			link, err := saveLink(app, req, request, domain, hash)
+			if errors.Is(err, helper.ErrShortCodeTaken) {
+				utils.WriteJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+				return
+			}
+			if err != nil {
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			metrics.UrlsShortenedTotal.Inc()
+			go fetchLinkTitle(app, link)
+			publishLinkEvent(app, models.EventLinkCreated, link)
+
+			if err := r1.Set(redisUtil.Ctx, linkKey(domain, hash), request.URL, request.Expiry*3600*time.Second).Err(); err != nil {
+				slog.ErrorContext(req.Context(), "Failed to cache short URL", "hash", hash, "error", err)
 			}
-			val, _ := r1.Set(redisUtil.Ctx, hash, request.URL, request.Expiry*3600*time.Second).Result()
-			log.Println("Printing result of set in shorten: ", val)
 			resp := new(Response)
 			r2.Decr(redisUtil.Ctx, ip)
 
-			val, _ = r2.Get(redisUtil.Ctx, ip).Result()
+			val, _ := r2.Get(redisUtil.Ctx, ip).Result()
 			resp.XRateRemaining, _ = strconv.Atoi(val)
 			ttl, _ := r2.TTL(redisUtil.Ctx, ip).Result()
 			resp.XTimeRemaining = int(ttl / time.Nanosecond / time.Minute)
-			resp.ShortUrl = os.Getenv("DOMAIN") + "/" + hash
+			resp.ShortUrl = shortURL(domain, hash)
 
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
@@ -122,3 +212,121 @@ func shorten(app *app.Application) http.HandlerFunc {
 
 	}
 }
+
+// shortURL builds the public URL of a short code on a branded domain, or on
+// the DOMAIN the service runs on when domain is nil
+func shortURL(domain *models.Domain, hash string) string {
+	if domain != nil {
+		return "https://" + domain.Hostname + "/" + hash
+	}
+	return os.Getenv("DOMAIN") + "/" + hash
+}
+
+// requestedDomain loads the branded domain a link is to be created on. It
+// must be verified and belong to one of the session user's teams. On failure
+// the HTTP status to respond with is returned alongside the error.
+func requestedDomain(app *app.Application, req *http.Request, request *Request) (*models.Domain, int, error) {
+	if request.Domain == "" {
+		return nil, http.StatusOK, nil
+	}
+
+	session, err := app.SessionStore.GetSession(req)
+	if err != nil {
+		return nil, http.StatusUnauthorized, errors.New("domain requires authentication")
+	}
+
+	domainStore := helper.NewDomainStore(app.DbConnector)
+	domain, err := domainStore.GetVerifiedDomain(req.Context(), request.Domain)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("failed to load domain")
+	}
+
+	role := ""
+	if domain != nil {
+		teamStore := helper.NewTeamStore(app.DbConnector)
+		if role, err = teamStore.GetMemberRole(req.Context(), domain.TeamID, session.UserID); err != nil {
+			return nil, http.StatusInternalServerError, errors.New("failed to load team")
+		}
+	}
+	if role == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("domain %q is not a verified domain of your teams", request.Domain)
+	}
+	return domain, http.StatusOK, nil
+}
+
+// applyRequestUTM merges the request's UTM fields and saved preset into request.URL
+func applyRequestUTM(app *app.Application, req *http.Request, request *Request) error {
+	utm := request.UTM
+	if request.UTMPreset != "" {
+		session, err := app.SessionStore.GetSession(req)
+		if err != nil {
+			return errors.New("utm_preset requires authentication")
+		}
+
+		presetStore := helper.NewUTMPresetStore(app.DbConnector)
+		preset, err := presetStore.GetPresetByName(req.Context(), session.UserID, request.UTMPreset)
+		if err != nil {
+			return errors.New("failed to load utm_preset")
+		}
+		if preset == nil {
+			return fmt.Errorf("utm_preset %q not found", request.UTMPreset)
+		}
+		utm = utm.Merge(preset.UTM)
+	}
+
+	merged, err := helper.ApplyUTM(request.URL, utm)
+	if err != nil {
+		return err
+	}
+	request.URL = merged
+	return nil
+}
+
+// saveLink persists the shortened URL, owned by the session user when there is one
+func saveLink(app *app.Application, req *http.Request, request *Request, domain *models.Domain, hash string) (*models.Link, error) {
+	link := &models.Link{
+		DomainID:     domainID(domain),
+		OriginalURL:  request.URL,
+		ShortCode:    hash,
+		CustomShort:  request.CustomShort != "",
+		Tags:         request.Tags,
+		ForwardQuery: request.ForwardQuery,
+		ForwardPath:  request.ForwardPath,
+		RoutingRules: request.RoutingRules,
+		DeepLink:     request.DeepLink,
+	}
+	if session, err := app.SessionStore.GetSession(req); err == nil {
+		link.UserID = &session.UserID
+	}
+	if request.Notes != "" {
+		link.Notes = &request.Notes
+	}
+	if request.PrivacyMode != "" {
+		link.PrivacyMode = &request.PrivacyMode
+	}
+	if request.Expiry > 0 {
+		expiresAt := time.Now().Add(request.Expiry * 3600 * time.Second)
+		link.ExpiresAt = &expiresAt
+	}
+
+	linkStore := helper.NewLinkStore(app.DbConnector)
+	if err := linkStore.CreateLink(req.Context(), link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// fetchLinkTitle looks up the destination page title in the background so it can be searched
+func fetchLinkTitle(app *app.Application, link *models.Link) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	title, err := helper.FetchPageTitle(ctx, link.OriginalURL)
+	if err != nil || title == "" {
+		slog.WarnContext(ctx, "Could not fetch page title", "short_code", link.ShortCode, "error", err)
+		return
+	}
+
+	linkStore := helper.NewLinkStore(app.DbConnector)
+	_ = linkStore.UpdateLinkTitle(ctx, link.ID, title)
+}
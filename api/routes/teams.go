@@ -0,0 +1,139 @@
+package routes
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shawty-ur/api/helper"
+	"shawty-ur/api/middleware"
+	"shawty-ur/api/models"
+	"shawty-ur/api/utils"
+	"shawty-ur/app"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TeamPayload is the request body for creating a team
+type TeamPayload struct {
+	Name string `json:"name"`
+}
+
+// TeamMemberPayload is the request body for adding a user to a team
+type TeamMemberPayload struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// RegisterTeamRoutes registers routes for managing teams and their members
+func RegisterTeamRoutes(r chi.Router, application *app.Application) {
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.RequireAuth(application.SessionStore))
+		r.Get("/teams", listTeamsHandler(application))
+		r.Post("/teams", createTeamHandler(application))
+		r.Post("/teams/{id}/members", addTeamMemberHandler(application))
+	})
+}
+
+func listTeamsHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := middleware.GetUserFromContext(r)
+
+		teamStore := helper.NewTeamStore(application.DbConnector)
+		teams, err := teamStore.ListTeamsForUser(r.Context(), session.UserID)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to list teams"})
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"teams": teams})
+	}
+}
+
+func createTeamHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := middleware.GetUserFromContext(r)
+
+		payload := new(TeamPayload)
+		if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+			return
+		}
+		team := &models.Team{Name: strings.TrimSpace(payload.Name)}
+		if team.Name == "" {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "A team name is required"})
+			return
+		}
+
+		teamStore := helper.NewTeamStore(application.DbConnector)
+		if err := teamStore.CreateTeam(r.Context(), team, session.UserID); err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create team"})
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusCreated, team)
+	}
+}
+
+// addTeamMemberHandler adds an existing user, by email, to a team. Only team
+// owners can add members.
+func addTeamMemberHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := middleware.GetUserFromContext(r)
+
+		teamID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid team id"})
+			return
+		}
+
+		payload := new(TeamMemberPayload)
+		if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+			return
+		}
+		if payload.Role == "" {
+			payload.Role = models.TeamRoleMember
+		}
+		if payload.Role != models.TeamRoleMember && payload.Role != models.TeamRoleOwner {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "role must be owner or member"})
+			return
+		}
+
+		teamStore := helper.NewTeamStore(application.DbConnector)
+		role, err := teamStore.GetMemberRole(r.Context(), teamID, session.UserID)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load team"})
+			return
+		}
+		if role != models.TeamRoleOwner {
+			utils.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "Team not found"})
+			return
+		}
+
+		userStore := helper.NewUserStore(application.DbConnector)
+		user, err := userStore.GetUserByEmail(r.Context(), strings.TrimSpace(payload.Email))
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load user"})
+			return
+		}
+		if user == nil {
+			utils.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+			return
+		}
+
+		if err := teamStore.AddMember(r.Context(), teamID, user.ID, payload.Role); err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to add team member"})
+			return
+		}
+
+		slog.InfoContext(r.Context(), "Team member added", "team_id", teamID, "user_id", user.ID, "role", payload.Role)
+		utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"team_id": teamID,
+			"user_id": user.ID,
+			"role":    payload.Role,
+		})
+	}
+}
@@ -3,8 +3,6 @@ package routes
 import (
 	"database/sql"
 	"encoding/json"
-	"fmt"
-	"log"
 	"log/slog"
 	"net/http"
 	"time"
@@ -98,7 +96,7 @@ func listUsersHandler(app *app.Application) http.HandlerFunc {
 		userStore := helper.NewUserStore(app.DbConnector)
 		userList, err := userStore.ListUsers(r.Context())
 		if err != nil {
-			slog.Error("Error getting user list")
+			slog.ErrorContext(r.Context(), "Error getting user list")
 			utils.WriteJSON(w, http.StatusInternalServerError, "Internal server Error")
 		}
 
@@ -115,14 +113,14 @@ func createUserHandler(app *app.Application) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		request := new(models.UserPayload)
 		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
-			slog.Error("Error Decoding user request body !!", "err: ", err)
+			slog.ErrorContext(req.Context(), "Error Decoding user request body !!", "err: ", err)
 			utils.WriteJSON(w, http.StatusBadRequest, "BAD Login Request")
 			return
 		}
 		user := new(models.User)
 		user.Password.Text = request.Password
 		if err := user.HashPassword(request.Password); err != nil {
-			slog.Error("Error in hashing User Password ", "err: ", err)
+			slog.ErrorContext(req.Context(), "Error in hashing User Password ", "err: ", err)
 			utils.WriteJSON(w, http.StatusInternalServerError, "Failed to process password")
 			return
 		}
@@ -133,19 +131,19 @@ func createUserHandler(app *app.Application) http.HandlerFunc {
 			user.Username = request.Username
 		}
 		user.Username = request.Username
-		slog.Info("Creating User: ", "email: ", user.Email, "Password: ", user.Password.Text)
+		slog.InfoContext(req.Context(), "Creating user", "username", user.Username)
 		txErr := db.WithTx(app.DbConnector, req.Context(), func(tx *sql.Tx) error {
 			userStore := helper.NewUserStore(app.DbConnector)
 
 			if err := userStore.CreateUser(req.Context(), tx, user); err != nil {
-				slog.Error("Error running create User query !!", "err", err)
+				slog.ErrorContext(req.Context(), "Error running create User query !!", "err", err)
 				return err // Return error to rollback transaction
 			}
 			return nil
 		})
 
 		if txErr != nil {
-			slog.Error("Error in create user tx!!! ", "err", txErr)
+			slog.ErrorContext(req.Context(), "Error in create user tx!!! ", "err", txErr)
 			utils.WriteJSON(w, http.StatusInternalServerError, "Error creating new User!!!")
 			return
 		}
@@ -178,19 +176,19 @@ func loginUserHandler(app *app.Application) http.HandlerFunc {
 
 			err := userStore.GetUserByUsernameOrEmail(req.Context(), tx, user)
 			if err != nil {
-				log.Printf("ERROR:: Cannot Find user with username: %s, email: %s", user.Username, user.Email)
+				slog.WarnContext(req.Context(), "Cannot find user to log in", "username", user.Username, "error", err)
 				return err // FIXED: Return error instead of writing response
 			}
 
 			if err := user.CompareHash(); err != nil {
-				slog.Error("Cannot login Invalid Password", "err: ", err)
+				slog.ErrorContext(req.Context(), "Cannot login Invalid Password", "err: ", err)
 				return err // FIXED: Return error to trigger txErr check
 			}
 			return nil
 		})
 
 		if txErr != nil {
-			slog.Error("Error in login user tx!!! ", "err", txErr)
+			slog.ErrorContext(req.Context(), "Error in login user tx!!! ", "err", txErr)
 			utils.WriteJSON(w, http.StatusForbidden, map[string]string{"error": "Invalid Username/Password"})
 			return
 		}
@@ -216,7 +214,7 @@ func getUserHandler(app *app.Application) http.HandlerFunc {
 func deleteUserHandler(app *app.Application) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := chi.URLParam(r, "id")
-		fmt.Println("id: ", id)
+		slog.DebugContext(r.Context(), "Deleting user", "id", id)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
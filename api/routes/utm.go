@@ -0,0 +1,108 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"shawty-ur/api/helper"
+	"shawty-ur/api/middleware"
+	"shawty-ur/api/models"
+	"shawty-ur/api/utils"
+	"shawty-ur/app"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// UTMPresetPayload is the request body for saving a UTM preset
+type UTMPresetPayload struct {
+	Name string           `json:"name"`
+	UTM  models.UTMParams `json:"utm"`
+}
+
+// RegisterUTMRoutes registers routes for managing saved UTM presets
+func RegisterUTMRoutes(r chi.Router, application *app.Application) {
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.RequireAuth(application.SessionStore))
+		r.Get("/utm-presets", listUTMPresetsHandler(application))
+		r.Put("/utm-presets", saveUTMPresetHandler(application))
+		r.Delete("/utm-presets/{name}", deleteUTMPresetHandler(application))
+	})
+}
+
+func listUTMPresetsHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := middleware.GetUserFromContext(r)
+
+		presetStore := helper.NewUTMPresetStore(application.DbConnector)
+		presets, err := presetStore.ListPresets(r.Context(), session.UserID)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to list UTM presets"})
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"presets": presets})
+	}
+}
+
+func saveUTMPresetHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := middleware.GetUserFromContext(r)
+
+		payload := new(UTMPresetPayload)
+		if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+			return
+		}
+		payload.Name = strings.TrimSpace(payload.Name)
+		if payload.Name == "" || len(payload.UTM.Values()) == 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "A name and at least one UTM parameter are required"})
+			return
+		}
+		if utf8.RuneCountInString(payload.Name) > models.MaxUTMPresetNameLength {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("name must be at most %d characters", models.MaxUTMPresetNameLength)})
+			return
+		}
+		if err := payload.UTM.Validate(); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		preset := &models.UTMPreset{
+			UserID: session.UserID,
+			Name:   payload.Name,
+			UTM:    payload.UTM,
+		}
+		presetStore := helper.NewUTMPresetStore(application.DbConnector)
+		if err := presetStore.SavePreset(r.Context(), preset); err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to save UTM preset"})
+			return
+		}
+
+		slog.InfoContext(r.Context(), "UTM preset saved", "user_id", session.UserID, "name", preset.Name)
+		utils.WriteJSON(w, http.StatusOK, preset)
+	}
+}
+
+func deleteUTMPresetHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := middleware.GetUserFromContext(r)
+		name := chi.URLParam(r, "name")
+
+		presetStore := helper.NewUTMPresetStore(application.DbConnector)
+		found, err := presetStore.DeletePreset(r.Context(), session.UserID, name)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete UTM preset"})
+			return
+		}
+		if !found {
+			utils.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "UTM preset not found"})
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,226 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"shawty-ur/api/helper"
+	"shawty-ur/api/middleware"
+	"shawty-ur/api/models"
+	"shawty-ur/api/safehttp"
+	"shawty-ur/api/utils"
+	"shawty-ur/api/webhook"
+	"shawty-ur/app"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// WebhookPayload is the request body for creating a webhook endpoint
+type WebhookPayload struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Share of clicks sent as link.clicked, between 0 and 1 (default 1)
+	ClickSampleRate *float64 `json:"click_sample_rate"`
+}
+
+// RegisterWebhookRoutes registers routes for managing webhook endpoints and their deliveries
+func RegisterWebhookRoutes(r chi.Router, application *app.Application) {
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.RequireAuth(application.SessionStore))
+		r.Get("/webhooks", listWebhooksHandler(application))
+		r.Post("/webhooks", createWebhookHandler(application))
+		r.Delete("/webhooks/{id}", deleteWebhookHandler(application))
+		r.Get("/webhooks/{id}/deliveries", listDeliveriesHandler(application))
+		r.Post("/webhooks/{id}/deliveries/{deliveryID}/redeliver", redeliverHandler(application))
+	})
+}
+
+// publishLinkEvent queues a webhook event about a link in the background
+func publishLinkEvent(application *app.Application, event string, link *models.Link) {
+	go webhook.Publish(context.Background(), application.DbConnector, link.UserID, event, map[string]any{"link": link})
+}
+
+// ownedWebhook loads the endpoint named by the {id} URL param, writing a 404
+// and returning nil unless it belongs to the session user
+func ownedWebhook(w http.ResponseWriter, r *http.Request, application *app.Application) *models.WebhookEndpoint {
+	session, _ := middleware.GetUserFromContext(r)
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid webhook id"})
+		return nil
+	}
+
+	webhookStore := helper.NewWebhookStore(application.DbConnector)
+	endpoint, err := webhookStore.GetEndpoint(r.Context(), session.UserID, id)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to load webhook"})
+		return nil
+	}
+	if endpoint == nil {
+		utils.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "Webhook not found"})
+		return nil
+	}
+	return endpoint
+}
+
+func listWebhooksHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := middleware.GetUserFromContext(r)
+
+		webhookStore := helper.NewWebhookStore(application.DbConnector)
+		endpoints, err := webhookStore.ListEndpoints(r.Context(), session.UserID)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to list webhooks"})
+			return
+		}
+		// The signing secret is only shown once, when the endpoint is created
+		for _, endpoint := range endpoints {
+			endpoint.Secret = ""
+		}
+
+		utils.WriteJSON(w, http.StatusOK, map[string]interface{}{"webhooks": endpoints})
+	}
+}
+
+// createWebhookHandler registers an endpoint. The response carries the
+// signing secret, which isn't returned again.
+func createWebhookHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := middleware.GetUserFromContext(r)
+
+		payload := new(WebhookPayload)
+		if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+			return
+		}
+		endpoint, err := validateWebhook(r.Context(), payload)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		endpoint.UserID = session.UserID
+
+		webhookStore := helper.NewWebhookStore(application.DbConnector)
+		if err := webhookStore.CreateEndpoint(r.Context(), endpoint); err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create webhook"})
+			return
+		}
+		webhook.ForgetClickSubscribers(r.Context(), application.RedisClient, endpoint.UserID)
+
+		utils.WriteJSON(w, http.StatusCreated, endpoint)
+	}
+}
+
+// validateWebhook checks a webhook payload, including that its URL is a
+// public address. Deliveries check the address again when connecting.
+func validateWebhook(ctx context.Context, payload *WebhookPayload) (*models.WebhookEndpoint, error) {
+	if err := safehttp.CheckURL(ctx, payload.URL); err != nil {
+		if errors.Is(err, safehttp.ErrForbiddenAddress) {
+			return nil, fmt.Errorf("url must point to a public address")
+		}
+		return nil, err
+	}
+
+	if len(payload.Events) == 0 {
+		return nil, fmt.Errorf("at least one event is required, from %v", models.WebhookEvents)
+	}
+	for _, event := range payload.Events {
+		if !slices.Contains(models.WebhookEvents, event) {
+			return nil, fmt.Errorf("unknown event %q, must be one of %v", event, models.WebhookEvents)
+		}
+	}
+
+	rate := 1.0
+	if payload.ClickSampleRate != nil {
+		rate = *payload.ClickSampleRate
+	}
+	if rate < 0 || rate > 1 {
+		return nil, fmt.Errorf("click_sample_rate must be between 0 and 1")
+	}
+
+	return &models.WebhookEndpoint{
+		URL:             payload.URL,
+		Events:          slices.Compact(slices.Sorted(slices.Values(payload.Events))),
+		ClickSampleRate: rate,
+	}, nil
+}
+
+func deleteWebhookHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoint := ownedWebhook(w, r, application)
+		if endpoint == nil {
+			return
+		}
+
+		webhookStore := helper.NewWebhookStore(application.DbConnector)
+		if err := webhookStore.DeleteEndpoint(r.Context(), endpoint.UserID, endpoint.ID); err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete webhook"})
+			return
+		}
+		webhook.ForgetClickSubscribers(r.Context(), application.RedisClient, endpoint.UserID)
+
+		slog.InfoContext(r.Context(), "Webhook endpoint deleted", "id", endpoint.ID, "user_id", endpoint.UserID)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// listDeliveriesHandler shows the delivery log of an endpoint, newest first
+func listDeliveriesHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoint := ownedWebhook(w, r, application)
+		if endpoint == nil {
+			return
+		}
+		page, pageSize := parsePagination(r)
+
+		webhookStore := helper.NewWebhookStore(application.DbConnector)
+		deliveries, total, err := webhookStore.ListDeliveries(r.Context(), endpoint.ID, pageSize, (page-1)*pageSize)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to list deliveries"})
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"deliveries": deliveries,
+			"total":      total,
+			"page":       page,
+			"page_size":  pageSize,
+		})
+	}
+}
+
+// redeliverHandler queues a past delivery to be sent again right away
+func redeliverHandler(application *app.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoint := ownedWebhook(w, r, application)
+		if endpoint == nil {
+			return
+		}
+
+		deliveryID, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid delivery id"})
+			return
+		}
+
+		webhookStore := helper.NewWebhookStore(application.DbConnector)
+		delivery, err := webhookStore.Redeliver(r.Context(), endpoint.ID, deliveryID)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to redeliver"})
+			return
+		}
+		if delivery == nil {
+			utils.WriteJSON(w, http.StatusNotFound, map[string]string{"error": "Delivery not found"})
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusAccepted, delivery)
+	}
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"context"
+	"testing"
+
+	"shawty-ur/api/models"
+)
+
+func TestValidateWebhook(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://93.184.216.34/hooks", false},
+		{"http://127.0.0.1:8080/hooks", true},
+		{"http://169.254.169.254/latest/meta-data", true},
+		{"http://10.0.0.5/hooks", true},
+		{"http://[::1]/hooks", true},
+		{"ftp://93.184.216.34/hooks", true},
+		{"hooks", true},
+	}
+	for _, tt := range tests {
+		payload := &WebhookPayload{URL: tt.url, Events: []string{models.EventLinkBroken}}
+		if _, err := validateWebhook(context.Background(), payload); (err != nil) != tt.wantErr {
+			t.Errorf("validateWebhook(%q) = %v, want error %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package routing
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"shawty-ur/api/geoip"
+	"shawty-ur/api/useragent"
+)
+
+// MaxRules caps how many rules a single link can carry
+const MaxRules = 20
+
+var (
+	validDevices = []string{useragent.DeviceDesktop, useragent.DeviceMobile, useragent.DeviceTablet}
+	validOS      = []string{
+		useragent.OSIOS, useragent.OSAndroid, useragent.OSWindows,
+		useragent.OSMacOS, useragent.OSLinux, useragent.OSChromeOS, useragent.OSOther,
+	}
+	countryPattern = regexp.MustCompile(`^[A-Za-z]{2}$`)
+	regionPattern  = regexp.MustCompile(`^[A-Za-z]{2}-[A-Za-z0-9]{1,3}$`)
+)
+
+// Rule sends visitors matching all of its conditions to Destination.
+// Within a condition any listed value matches; empty conditions match everyone.
+type Rule struct {
+	Name        string    `json:"name,omitempty"`
+	Devices     []string  `json:"devices,omitempty"`
+	OS          []string  `json:"os,omitempty"`
+	Countries   []string  `json:"countries,omitempty"` // ISO 3166-1 alpha-2, e.g. "DE"
+	Regions     []string  `json:"regions,omitempty"`   // ISO 3166-2, e.g. "US-CA"
+	Schedule    *Schedule `json:"schedule,omitempty"`
+	Destination string    `json:"destination"`
+}
+
+// Rules is the ordered list of conditional redirects of a link, stored as JSONB.
+// The first matching rule wins; when none match the link's own URL is the fallback.
+type Rules []Rule
+
+// Visitor holds the request attributes rules are evaluated against
+type Visitor struct {
+	Device  string    `json:"device"`
+	OS      string    `json:"os"`
+	Country string    `json:"country,omitempty"`
+	Region  string    `json:"region,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// VisitorFromRequest builds the Visitor for an incoming redirect request
+// made from loc
+func VisitorFromRequest(req *http.Request, loc geoip.Location) Visitor {
+	ua := useragent.Parse(req.UserAgent())
+	return Visitor{
+		Device:  ua.Device,
+		OS:      ua.OS,
+		Country: loc.Country,
+		Region:  loc.Region,
+		Time:    time.Now(),
+	}
+}
+
+// Match returns the destination of the first rule matching the visitor
+func (rules Rules) Match(visitor Visitor) (string, bool) {
+	if i := rules.MatchIndex(visitor); i >= 0 {
+		return rules[i].Destination, true
+	}
+	return "", false
+}
+
+// MatchIndex returns the index of the first rule matching the visitor, or -1
+func (rules Rules) MatchIndex(visitor Visitor) int {
+	for i, rule := range rules {
+		if rule.matches(visitor) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (rule Rule) matches(visitor Visitor) bool {
+	return matchAny(rule.Devices, visitor.Device) &&
+		matchAny(rule.OS, visitor.OS) &&
+		matchAnyFold(rule.Countries, visitor.Country) &&
+		matchAnyFold(rule.Regions, visitor.Region) &&
+		(rule.Schedule == nil || rule.Schedule.matches(visitor.Time))
+}
+
+func matchAny(values []string, value string) bool {
+	return len(values) == 0 || slices.Contains(values, value)
+}
+
+// matchAnyFold is matchAny for case-insensitive codes; an unknown (empty)
+// value never matches a non-empty list
+func matchAnyFold(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	return value != "" && slices.ContainsFunc(values, func(v string) bool {
+		return strings.EqualFold(v, value)
+	})
+}
+
+// Validate checks that every rule has a usable destination, at least one
+// condition, and only known condition values
+func (rules Rules) Validate() error {
+	if len(rules) > MaxRules {
+		return fmt.Errorf("at most %d routing rules are allowed", MaxRules)
+	}
+
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("routing rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (rule Rule) validate() error {
+	u, err := url.Parse(rule.Destination)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("destination must be an absolute http(s) url")
+	}
+
+	if len(rule.Devices) == 0 && len(rule.OS) == 0 && len(rule.Countries) == 0 && len(rule.Regions) == 0 &&
+		rule.Schedule == nil {
+		return errors.New("at least one condition is required")
+	}
+	if rule.Schedule != nil {
+		if err := rule.Schedule.validate(); err != nil {
+			return err
+		}
+	}
+	if err := checkValues("devices", rule.Devices, validDevices); err != nil {
+		return err
+	}
+	if err := checkValues("os", rule.OS, validOS); err != nil {
+		return err
+	}
+	if err := checkPattern("countries", rule.Countries, countryPattern); err != nil {
+		return err
+	}
+	return checkPattern("regions", rule.Regions, regionPattern)
+}
+
+func checkValues(field string, values, valid []string) error {
+	for _, value := range values {
+		if !slices.Contains(valid, value) {
+			return fmt.Errorf("unknown %s value %q, expected one of %v", field, value, valid)
+		}
+	}
+	return nil
+}
+
+func checkPattern(field string, values []string, pattern *regexp.Regexp) error {
+	for _, value := range values {
+		if !pattern.MatchString(value) {
+			return fmt.Errorf("invalid %s value %q", field, value)
+		}
+	}
+	return nil
+}
+
+// Value implements driver.Valuer so Rules can be written to a JSONB column
+func (rules Rules) Value() (driver.Value, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(rules)
+}
+
+// Scan implements sql.Scanner so Rules can be read from a JSONB column
+func (rules *Rules) Scan(src any) error {
+	switch data := src.(type) {
+	case nil:
+		*rules = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(data, rules)
+	case string:
+		return json.Unmarshal([]byte(data), rules)
+	}
+	return fmt.Errorf("cannot scan %T into routing.Rules", src)
+}
@@ -0,0 +1,118 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"shawty-ur/api/useragent"
+)
+
+func TestRulesMatch(t *testing.T) {
+	rules := Rules{
+		{Devices: []string{useragent.DeviceMobile}, OS: []string{useragent.OSIOS}, Destination: "https://apps.apple.com/app"},
+		{Devices: []string{useragent.DeviceMobile}, Destination: "https://m.example.com"},
+		{OS: []string{useragent.OSWindows, useragent.OSMacOS}, Destination: "https://example.com/desktop"},
+		{Countries: []string{"de", "AT"}, Destination: "https://example.de"},
+		{Regions: []string{"US-CA"}, Destination: "https://example.com/ca"},
+	}
+	tests := []struct {
+		name    string
+		visitor Visitor
+		want    string
+		wantOK  bool
+	}{
+		{"first match wins", Visitor{Device: useragent.DeviceMobile, OS: useragent.OSIOS}, "https://apps.apple.com/app", true},
+		{"later rule", Visitor{Device: useragent.DeviceMobile, OS: useragent.OSAndroid}, "https://m.example.com", true},
+		{"any listed value", Visitor{Device: useragent.DeviceDesktop, OS: useragent.OSMacOS}, "https://example.com/desktop", true},
+		{"country is case-insensitive", Visitor{Device: useragent.DeviceDesktop, OS: useragent.OSLinux, Country: "DE"}, "https://example.de", true},
+		{"region", Visitor{Device: useragent.DeviceDesktop, OS: useragent.OSLinux, Country: "US", Region: "us-ca"}, "https://example.com/ca", true},
+		{"unknown country matches no list", Visitor{Device: useragent.DeviceDesktop, OS: useragent.OSLinux}, "", false},
+		{"no match", Visitor{Device: useragent.DeviceDesktop, OS: useragent.OSLinux, Country: "FR"}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := rules.Match(tt.visitor)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Match() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRulesValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{"valid", Rule{Devices: []string{useragent.DeviceMobile}, Destination: "https://m.example.com"}, false},
+		{"relative destination", Rule{Devices: []string{useragent.DeviceMobile}, Destination: "/m"}, true},
+		{"no conditions", Rule{Destination: "https://example.com"}, true},
+		{"unknown device", Rule{Devices: []string{"watch"}, Destination: "https://example.com"}, true},
+		{"unknown os", Rule{OS: []string{"beos"}, Destination: "https://example.com"}, true},
+		{"valid country", Rule{Countries: []string{"DE"}, Destination: "https://example.de"}, false},
+		{"bad country", Rule{Countries: []string{"DEU"}, Destination: "https://example.com"}, true},
+		{"bad region", Rule{Regions: []string{"California"}, Destination: "https://example.com"}, true},
+		{"valid schedule", Rule{Schedule: &Schedule{TimeZone: "Europe/Berlin", Weekdays: []string{"Mon"}}, Destination: "https://example.com"}, false},
+		{"unknown time zone", Rule{Schedule: &Schedule{TimeZone: "Mars/Olympus"}, Destination: "https://example.com"}, true},
+		{"unknown weekday", Rule{Schedule: &Schedule{TimeZone: "UTC", Weekdays: []string{"someday"}}, Destination: "https://example.com"}, true},
+		{"empty hours", Rule{Schedule: &Schedule{TimeZone: "UTC", Hours: []HourRange{{"09:00", "09:00"}}}, Destination: "https://example.com"}, true},
+		{"bad hours", Rule{Schedule: &Schedule{TimeZone: "UTC", Hours: []HourRange{{"9am", "5pm"}}}, Destination: "https://example.com"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (Rules{tt.rule}).Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	tooMany := make(Rules, MaxRules+1)
+	for i := range tooMany {
+		tooMany[i] = Rule{Devices: []string{useragent.DeviceMobile}, Destination: "https://m.example.com"}
+	}
+	if err := tooMany.Validate(); err == nil {
+		t.Errorf("Validate() of %d rules succeeded, want an error", len(tooMany))
+	}
+}
+
+func TestScheduleMatches(t *testing.T) {
+	businessHours := &Schedule{
+		TimeZone: "Europe/Berlin",
+		Weekdays: []string{"mon", "tue", "wed", "thu", "fri"},
+		Hours:    []HourRange{{"09:00", "17:00"}},
+	}
+	weekends := &Schedule{TimeZone: "Europe/Berlin", Weekdays: []string{"Sat", "sun"}}
+	overnight := &Schedule{TimeZone: "UTC", Hours: []HourRange{{"22:00", "06:00"}}}
+
+	tests := []struct {
+		name     string
+		schedule *Schedule
+		at       string
+		want     bool
+	}{
+		// 2026-01-05 is a Monday; Berlin is UTC+1 in winter
+		{"business hours", businessHours, "2026-01-05T10:00:00Z", true},
+		{"start is included", businessHours, "2026-01-05T08:00:00Z", true},
+		{"end is excluded", businessHours, "2026-01-05T16:00:00Z", false},
+		{"before opening in local time", businessHours, "2026-01-05T07:30:00Z", false},
+		{"weekend", businessHours, "2026-01-10T10:00:00Z", false},
+		{"local weekday differs from UTC", weekends, "2026-01-09T23:30:00Z", true},
+		{"weekday", weekends, "2026-01-09T22:30:00Z", false},
+		{"overnight before midnight", overnight, "2026-01-05T23:00:00Z", true},
+		{"overnight after midnight", overnight, "2026-01-06T05:59:00Z", true},
+		{"overnight during the day", overnight, "2026-01-06T12:00:00Z", false},
+		{"unknown time zone", &Schedule{TimeZone: "Mars/Olympus"}, "2026-01-05T10:00:00Z", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, err := time.Parse(time.RFC3339, tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.schedule.matches(at); got != tt.want {
+				t.Errorf("matches(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // Time zones must resolve even on hosts without zoneinfo
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Schedule restricts a rule to certain local times, e.g. business hours.
+// Hours are "HH:MM" ranges including Start and excluding End; a range whose
+// End is before its Start runs past midnight ("22:00"-"06:00"). Weekdays are
+// checked against the local date of the visit.
+type Schedule struct {
+	TimeZone string      `json:"time_zone"`          // IANA name, e.g. "Europe/Berlin"
+	Weekdays []string    `json:"weekdays,omitempty"` // "mon".."sun"; empty means every day
+	Hours    []HourRange `json:"hours,omitempty"`    // empty means all day
+}
+
+// HourRange is a local time-of-day window
+type HourRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+var locations sync.Map // time zone name -> *time.Location
+
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// parseClock converts "HH:MM" into minutes since midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (s *Schedule) matches(at time.Time) bool {
+	loc, err := loadLocation(s.TimeZone)
+	if err != nil {
+		return false
+	}
+	local := at.In(loc)
+
+	if len(s.Weekdays) > 0 && !slices.ContainsFunc(s.Weekdays, func(day string) bool {
+		return weekdays[strings.ToLower(day)] == local.Weekday()
+	}) {
+		return false
+	}
+
+	if len(s.Hours) == 0 {
+		return true
+	}
+	minute := local.Hour()*60 + local.Minute()
+	for _, hours := range s.Hours {
+		start, errStart := parseClock(hours.Start)
+		end, errEnd := parseClock(hours.End)
+		if errStart != nil || errEnd != nil {
+			continue
+		}
+		if start <= end && minute >= start && minute < end {
+			return true
+		}
+		if start > end && (minute >= start || minute < end) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schedule) validate() error {
+	if s.TimeZone == "" {
+		return errors.New("schedule time_zone is required")
+	}
+	if _, err := loadLocation(s.TimeZone); err != nil {
+		return fmt.Errorf("unknown schedule time_zone %q", s.TimeZone)
+	}
+
+	for _, day := range s.Weekdays {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("unknown schedule weekday %q, expected mon..sun", day)
+		}
+	}
+
+	for _, hours := range s.Hours {
+		start, err := parseClock(hours.Start)
+		if err != nil {
+			return fmt.Errorf("schedule hours: %w", err)
+		}
+		end, err := parseClock(hours.End)
+		if err != nil {
+			return fmt.Errorf("schedule hours: %w", err)
+		}
+		if start == end {
+			return fmt.Errorf("schedule hours %s-%s are empty", hours.Start, hours.End)
+		}
+	}
+	return nil
+}
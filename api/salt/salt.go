@@ -0,0 +1,47 @@
+// Package salt hands out random salts that change every day (UTC). A day's
+// salt is shared by all replicas through Redis and expires soon after the
+// day ends, after which whatever was hashed with it can no longer be linked
+// back to anyone.
+package salt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// dayLayout formats the day part of the salt keys (UTC)
+	dayLayout = "2006-01-02"
+	// ttl keeps a day's salt just long enough for events around midnight
+	ttl = 26 * time.Hour
+)
+
+// Daily returns the salt of the day of at, stored under "<prefix>:<day>",
+// creating it if this is the day's first use. Callers pick their own prefix
+// so unrelated hashes don't share a salt.
+func Daily(ctx context.Context, rdb *redis.Client, prefix string, at time.Time) (string, error) {
+	key := prefix + ":" + at.UTC().Format(dayLayout)
+
+	value, err := rdb.Get(ctx, key).Result()
+	if err == nil {
+		return value, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	// Another replica may get there first, in which case its salt wins
+	if err := rdb.SetNX(ctx, key, hex.EncodeToString(b), ttl).Err(); err != nil {
+		return "", err
+	}
+	return rdb.Get(ctx, key).Result()
+}
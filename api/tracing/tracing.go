@@ -0,0 +1,81 @@
+// Package tracing sets up OpenTelemetry tracing: the tracer provider and its
+// exporter, W3C trace context propagation, and trace IDs in log records.
+//
+// HTTP routes, Postgres queries and Redis commands get their spans from the
+// instrumentation in middleware.TracingMiddleware, db.New and redisUtil.New.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"shawty-ur/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span exporters
+const (
+	ExporterNone   = "none"   // Don't record spans; incoming trace context is still passed on and logged
+	ExporterStdout = "stdout" // Print spans as JSON, for local debugging
+	ExporterOTLP   = "otlp"   // Send spans over OTLP/HTTP, configured with the OTEL_EXPORTER_OTLP_* variables
+)
+
+// Exporters lists the span exporters
+var Exporters = []string{ExporterNone, ExporterStdout, ExporterOTLP}
+
+// instrumentationName names the tracer of spans started by this service itself
+const instrumentationName = "shawty-ur"
+
+// Tracer starts the spans of work that isn't covered by the HTTP, SQL and
+// Redis instrumentation, like recording clicks in the background
+var Tracer trace.Tracer = otel.Tracer(instrumentationName)
+
+// Setup installs the tracer provider and the W3C trace context and baggage
+// propagators. The returned function flushes buffered spans on shutdown.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
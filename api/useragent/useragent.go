@@ -0,0 +1,70 @@
+package useragent
+
+import "strings"
+
+// Device types
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+)
+
+// OS families
+const (
+	OSIOS      = "ios"
+	OSAndroid  = "android"
+	OSWindows  = "windows"
+	OSMacOS    = "macos"
+	OSLinux    = "linux"
+	OSChromeOS = "chromeos"
+	OSOther    = "other"
+)
+
+// UserAgent is the parsed form of a User-Agent header
+type UserAgent struct {
+	Browser        string `json:"browser"`
+	BrowserVersion string `json:"browser_version,omitempty"` // Major version only
+	OS             string `json:"os"`
+	OSVersion      string `json:"os_version,omitempty"` // e.g. "17.2", "14" or "10.15"
+	Device         string `json:"device"`
+}
+
+// Parse extracts the browser, OS and device type from a User-Agent header.
+// Matching is done on well known tokens rather than a full grammar; anything
+// unrecognised is reported as BrowserOther and OSOther on a desktop.
+// Versions are cut down to the parts that matter for analytics, to keep the
+// number of distinct values small.
+func Parse(header string) UserAgent {
+	ua := strings.ToLower(header)
+
+	result := UserAgent{OS: parseOS(ua), Device: DeviceDesktop}
+	result.OSVersion = parseOSVersion(ua, result.OS)
+	result.Browser, result.BrowserVersion = parseBrowser(ua)
+	switch {
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"),
+		result.OS == OSAndroid && !strings.Contains(ua, "mobile"):
+		result.Device = DeviceTablet
+	case strings.Contains(ua, "mobi"), strings.Contains(ua, "iphone"), strings.Contains(ua, "ipod"):
+		result.Device = DeviceMobile
+	}
+	return result
+}
+
+func parseOS(ua string) string {
+	switch {
+	// iOS user agents also contain "like Mac OS X", so check them first
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return OSIOS
+	case strings.Contains(ua, "android"):
+		return OSAndroid
+	case strings.Contains(ua, "windows"):
+		return OSWindows
+	case strings.Contains(ua, "cros"):
+		return OSChromeOS
+	case strings.Contains(ua, "mac os x"), strings.Contains(ua, "macintosh"):
+		return OSMacOS
+	case strings.Contains(ua, "linux"):
+		return OSLinux
+	}
+	return OSOther
+}
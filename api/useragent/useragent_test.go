@@ -0,0 +1,67 @@
+package useragent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   UserAgent
+	}{
+		{
+			name:   "chrome on windows",
+			header: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want:   UserAgent{Browser: BrowserChrome, BrowserVersion: "120", OS: OSWindows, OSVersion: "10", Device: DeviceDesktop},
+		},
+		{
+			name:   "safari on iphone",
+			header: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			want:   UserAgent{Browser: BrowserSafari, BrowserVersion: "17", OS: OSIOS, OSVersion: "17.2", Device: DeviceMobile},
+		},
+		{
+			name:   "edge on macos",
+			header: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			want:   UserAgent{Browser: BrowserEdge, BrowserVersion: "120", OS: OSMacOS, OSVersion: "10.15", Device: DeviceDesktop},
+		},
+		{
+			name:   "android tablet",
+			header: "Mozilla/5.0 (Linux; Android 14; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want:   UserAgent{Browser: BrowserChrome, BrowserVersion: "120", OS: OSAndroid, OSVersion: "14", Device: DeviceTablet},
+		},
+		{
+			name:   "instagram in-app",
+			header: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Instagram 309.0.1.22.111",
+			want:   UserAgent{Browser: BrowserInstagram, BrowserVersion: "309", OS: OSIOS, OSVersion: "17.1", Device: DeviceMobile},
+		},
+		{
+			name:   "unknown",
+			header: "curl/8.4.0",
+			want:   UserAgent{Browser: BrowserOther, OS: OSOther, Device: DeviceDesktop},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.header); got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBoundsVersions(t *testing.T) {
+	long := strings.Repeat("9", 40)
+	headers := []string{
+		"Mozilla/5.0 (Windows NT 10.0) Chrome/" + long,
+		"Mozilla/5.0 (iPhone; CPU iPhone OS " + long + "_" + long + " like Mac OS X) Version/" + long + " Safari/604.1",
+		"Mozilla/5.0 (Linux; Android " + long + "; Mobile) Firefox/" + long,
+	}
+	for _, header := range headers {
+		ua := Parse(header)
+		if len(ua.BrowserVersion) > 16 || len(ua.OSVersion) > 16 {
+			t.Errorf("Parse(%q) = %+v, versions longer than 16 characters", header, ua)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package useragent
+
+import "regexp"
+
+// Browser families
+const (
+	BrowserChrome    = "chrome"
+	BrowserSafari    = "safari"
+	BrowserFirefox   = "firefox"
+	BrowserEdge      = "edge"
+	BrowserOpera     = "opera"
+	BrowserSamsung   = "samsung"
+	BrowserYandex    = "yandex"
+	BrowserIE        = "ie"
+	BrowserFacebook  = "facebook"  // In-app browser
+	BrowserInstagram = "instagram" // In-app browser
+	BrowserOther     = "other"
+)
+
+// browserTokens are checked in order: most browsers also claim to be Safari
+// and Chrome, so the more specific tokens come first. Versions are captured
+// up to 6 digits, here and for OSes, so even a forged header fits the 16
+// characters a version is stored in.
+var browserTokens = []struct {
+	family string
+	token  *regexp.Regexp
+}{
+	{BrowserFacebook, regexp.MustCompile(`fb(?:av|_iab)/(\d{1,6})`)},
+	{BrowserInstagram, regexp.MustCompile(`instagram (\d{1,6})`)},
+	{BrowserEdge, regexp.MustCompile(`edg(?:e|a|ios)?/(\d{1,6})`)},
+	{BrowserOpera, regexp.MustCompile(`(?:opr|opios|opera)/(\d{1,6})`)},
+	{BrowserSamsung, regexp.MustCompile(`samsungbrowser/(\d{1,6})`)},
+	{BrowserYandex, regexp.MustCompile(`yabrowser/(\d{1,6})`)},
+	{BrowserFirefox, regexp.MustCompile(`(?:firefox|fxios)/(\d{1,6})`)},
+	{BrowserChrome, regexp.MustCompile(`(?:chrome|crios)/(\d{1,6})`)},
+	{BrowserSafari, regexp.MustCompile(`version/(\d{1,6}).*safari/`)},
+	{BrowserIE, regexp.MustCompile(`(?:msie |trident/.*rv:)(\d{1,6})`)},
+}
+
+func parseBrowser(ua string) (family, version string) {
+	for _, b := range browserTokens {
+		if match := b.token.FindStringSubmatch(ua); match != nil {
+			return b.family, match[1]
+		}
+	}
+	return BrowserOther, ""
+}
+
+var (
+	iosVersion      = regexp.MustCompile(`os (\d{1,6})[_.](\d{1,6})`)
+	androidVersion  = regexp.MustCompile(`android (\d{1,6})`)
+	windowsVersion  = regexp.MustCompile(`windows nt (\d+\.\d+)`)
+	macOSVersion    = regexp.MustCompile(`mac os x (\d{1,6})[_.](\d{1,6})`)
+	chromeOSVersion = regexp.MustCompile(`cros \S+ (\d{1,6})`)
+)
+
+// windowsReleases maps NT kernel versions to marketing names. Windows 11
+// still reports NT 10.0, so it can't be told apart from 10.
+var windowsReleases = map[string]string{
+	"10.0": "10",
+	"6.3":  "8.1",
+	"6.2":  "8",
+	"6.1":  "7",
+	"6.0":  "vista",
+	"5.1":  "xp",
+}
+
+func parseOSVersion(ua, os string) string {
+	switch os {
+	case OSIOS:
+		if m := iosVersion.FindStringSubmatch(ua); m != nil {
+			return m[1] + "." + m[2]
+		}
+	case OSAndroid:
+		if m := androidVersion.FindStringSubmatch(ua); m != nil {
+			return m[1]
+		}
+	case OSWindows:
+		if m := windowsVersion.FindStringSubmatch(ua); m != nil {
+			return windowsReleases[m[1]]
+		}
+	case OSMacOS:
+		// Browsers froze this at 10.15.7, so newer releases look like Catalina
+		if m := macOSVersion.FindStringSubmatch(ua); m != nil {
+			return m[1] + "." + m[2]
+		}
+	case OSChromeOS:
+		if m := chromeOSVersion.FindStringSubmatch(ua); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+)
+
+// ClientIP returns the visitor's IP address. RemoteAddr has already been
+// rewritten from X-Forwarded-For/X-Real-IP by the RealIP middleware, and may
+// or may not carry a port.
+func ClientIP(req *http.Request) string {
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return host
+	}
+	return req.RemoteAddr
+}
@@ -3,13 +3,13 @@ package db
 import (
 	"context"
 	"database/sql"
-	"fmt"
-	"log"
+	"errors"
 	"log/slog"
 	"strings"
 	"time"
 
-	_ "github.com/lib/pq" // PostgreSQL driver
+	"github.com/XSAM/otelsql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
 type DbConfig struct {
@@ -19,9 +19,8 @@ type DbConfig struct {
 }
 
 func New(dbConfig DbConfig) (*sql.DB, error) {
-	fmt.Printf("in side NEw \n")
 	if dbConfig.Dsn == "" {
-		log.Fatalf("Cannot Connect to DB, DSN not present")
+		return nil, errors.New("cannot connect to DB, DSN not present")
 	}
 
 	dsn := dbConfig.Dsn
@@ -32,11 +31,16 @@ func New(dbConfig DbConfig) (*sql.DB, error) {
 		dsn += "sslmode=disable"
 	}
 
-	db, err := sql.Open("postgres", dsn)
+	connector, err := newInstrumentedConnector(dsn)
 	if err != nil {
 		slog.Error("Error opening db connection !\n\n")
 		return nil, err
 	}
+	// Every query gets a span of its own, below the span of the request or job running it
+	db := otelsql.OpenDB(connector,
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+	)
 
 	db.SetMaxOpenConns(dbConfig.Max_open_conn)
 	db.SetMaxIdleConns(dbConfig.Max_idle_open)
@@ -50,7 +54,7 @@ func New(dbConfig DbConfig) (*sql.DB, error) {
 	defer cancel()
 
 	if err := db.PingContext(ctx); err != nil {
-		fmt.Printf("Cannot ping DB !! %s %s", dsn, err)
+		slog.ErrorContext(ctx, "Cannot ping DB !!", "error", err)
 		return nil, err
 	}
 	return db, nil
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"shawty-ur/api/metrics"
+
+	"github.com/lib/pq"
+)
+
+// queryTypes are the statement kinds query durations are labelled with;
+// anything else is counted as "other"
+var queryTypes = map[string]bool{
+	"select": true,
+	"insert": true,
+	"update": true,
+	"delete": true,
+	"with":   true,
+}
+
+// queryType labels a statement by its first keyword
+func queryType(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) > 0 && queryTypes[strings.ToLower(fields[0])] {
+		return strings.ToLower(fields[0])
+	}
+	return "other"
+}
+
+// instrumentedConnector opens PostgreSQL connections that time every query
+// into metrics.DatabaseQueryDuration
+type instrumentedConnector struct {
+	driver.Connector
+}
+
+// pqConn is everything lib/pq connections implement that database/sql uses
+type pqConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.QueryerContext
+	driver.ExecerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// newInstrumentedConnector wraps lib/pq's connector for dsn
+func newInstrumentedConnector(dsn string) (driver.Connector, error) {
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return instrumentedConnector{connector}, nil
+}
+
+func (c instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pc, ok := conn.(pqConn); ok {
+		return instrumentedConn{pc}, nil
+	}
+	return conn, nil
+}
+
+type instrumentedConn struct {
+	pqConn
+}
+
+func (c instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	defer observeQuery(query, time.Now())
+	return c.pqConn.QueryContext(ctx, query, args)
+}
+
+func (c instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	defer observeQuery(query, time.Now())
+	return c.pqConn.ExecContext(ctx, query, args)
+}
+
+func observeQuery(query string, start time.Time) {
+	metrics.DatabaseQueryDuration.WithLabelValues(queryType(query)).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,36 @@
+package redisUtil
+
+import (
+	"context"
+	"time"
+
+	"shawty-ur/api/metrics"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// metricsHook times every Redis command, and every pipeline as a whole, into
+// metrics.RedisCacheDuration
+type metricsHook struct{}
+
+func (metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		defer observe(cmd.Name(), time.Now())
+		return next(ctx, cmd)
+	}
+}
+
+func (metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		defer observe("pipeline", time.Now())
+		return next(ctx, cmds)
+	}
+}
+
+func observe(operation string, start time.Time) {
+	metrics.RedisCacheDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
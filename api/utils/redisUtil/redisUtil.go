@@ -8,6 +8,7 @@ import (
 
 	"shawty-ur/config"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -20,16 +21,20 @@ func New(cfg config.RedisConfig) (*redis.Client, error) {
 		Password: cfg.Password,
 		DB:       cfg.DB,
 	})
+	client.AddHook(metricsHook{})
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		slog.Warn("Failed to instrument Redis tracing", "error", err)
+	}
 
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
-		slog.Error("Cannot connect to Redis", "error", err)
+		slog.ErrorContext(ctx, "Cannot connect to Redis", "error", err)
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	slog.Info("Successfully connected to Redis", "addr", cfg.Addr)
+	slog.InfoContext(ctx, "Successfully connected to Redis", "addr", cfg.Addr)
 	return client, nil
 }
@@ -0,0 +1,72 @@
+// Package visitors estimates unique visitors per link with Redis HyperLogLogs.
+//
+// Visitors are identified by a fingerprint: a hash of their IP address and
+// user agent with a salt that changes every day (see package salt), after
+// which the fingerprints of that day can no longer be linked back to anyone.
+// As a consequence a visitor coming back on another day counts again.
+package visitors
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"shawty-ur/api/salt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// dayLayout formats the day part of the Redis keys (UTC)
+	dayLayout = "2006-01-02"
+	// saltPrefix prefixes the Redis keys of the fingerprint salts
+	saltPrefix = "visitors:salt"
+	// counterTTL is how long daily counters are kept for stats
+	counterTTL = 400 * 24 * time.Hour
+)
+
+// Key is the HyperLogLog holding the visitors of a link on a day
+func Key(linkID int64, day time.Time) string {
+	return "visitors:" + strconv.FormatInt(linkID, 10) + ":" + day.UTC().Format(dayLayout)
+}
+
+// Fingerprint hashes a visitor's IP address and user agent with a salt
+func Fingerprint(salt, ip, userAgent string) string {
+	sum := sha256.Sum256([]byte(salt + "\x00" + ip + "\x00" + userAgent))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Record adds a visitor of a link at the given time
+func Record(ctx context.Context, rdb *redis.Client, linkID int64, ip, userAgent string, at time.Time) error {
+	s, err := salt.Daily(ctx, rdb, saltPrefix, at)
+	if err != nil {
+		return err
+	}
+
+	key := Key(linkID, at)
+	pipe := rdb.TxPipeline()
+	pipe.PFAdd(ctx, key, Fingerprint(s, ip, userAgent))
+	pipe.Expire(ctx, key, counterTTL)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Count estimates the unique visitors of a link on the days (UTC) touched by
+// [from, to). The estimate has a standard error of about 0.81%.
+func Count(ctx context.Context, rdb *redis.Client, linkID int64, from, to time.Time) (int64, error) {
+	// Older counters have expired anyway
+	if oldest := time.Now().Add(-counterTTL); from.Before(oldest) {
+		from = oldest
+	}
+
+	var keys []string
+	for day := from.UTC().Truncate(24 * time.Hour); day.Before(to); day = day.Add(24 * time.Hour) {
+		keys = append(keys, Key(linkID, day))
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	return rdb.PFCount(ctx, keys...).Result()
+}
@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"shawty-ur/api/helper"
+	"shawty-ur/api/metrics"
+	"shawty-ur/api/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// clickSubscribersTTL is how long whether a user has link.clicked endpoints
+// is cached; endpoint changes also clear it right away
+const clickSubscribersTTL = 5 * time.Minute
+
+// Click is what link.clicked tells about a click. Like live events it leaves
+// out the IP address, user agent and full referrer.
+type Click struct {
+	ClickedAt        time.Time `json:"clicked_at"`
+	Country          string    `json:"country,omitempty"`
+	ReferrerDomain   string    `json:"referrer_domain,omitempty"`
+	ReferrerCategory string    `json:"referrer_category,omitempty"`
+	Browser          string    `json:"browser,omitempty"`
+	OS               string    `json:"os,omitempty"`
+	Device           string    `json:"device,omitempty"`
+	Bot              bool      `json:"bot"`
+	ViaQR            bool      `json:"via_qr"`
+	VariantID        *int64    `json:"variant_id,omitempty"`
+}
+
+// NewClick builds the link.clicked payload of a click
+func NewClick(click *models.Click) Click {
+	return Click{
+		ClickedAt:        click.ClickedAt.UTC(),
+		Country:          click.Country,
+		ReferrerDomain:   click.ReferrerDomain,
+		ReferrerCategory: click.ReferrerCategory,
+		Browser:          click.Browser,
+		OS:               click.OS,
+		Device:           click.Device,
+		Bot:              click.IsBot,
+		ViaQR:            click.ViaQR,
+		VariantID:        click.VariantID,
+	}
+}
+
+func clickSubscribersKey(userID int64) string {
+	return "webhooks:clicked:" + strconv.FormatInt(userID, 10)
+}
+
+// WantsClicks reports whether userID has an active endpoint subscribed to
+// link.clicked, so clicks of users without one skip the outbox entirely.
+// When it can't tell it says yes and leaves the decision to Publish.
+func WantsClicks(ctx context.Context, db *sql.DB, rdb *redis.Client, userID *int64) bool {
+	if userID == nil {
+		return false
+	}
+
+	key := clickSubscribersKey(*userID)
+	if cached, err := rdb.Get(ctx, key).Result(); err == nil {
+		metrics.CacheHits.WithLabelValues(metrics.CacheWebhooks).Inc()
+		return cached == "1"
+	}
+	metrics.CacheMisses.WithLabelValues(metrics.CacheWebhooks).Inc()
+
+	webhookStore := helper.NewWebhookStore(db)
+	wants, err := webhookStore.HasActiveEndpoint(ctx, *userID, models.EventLinkClicked)
+	if err != nil {
+		return true
+	}
+
+	value := "0"
+	if wants {
+		value = "1"
+	}
+	if err := rdb.Set(ctx, key, value, clickSubscribersTTL).Err(); err != nil {
+		slog.WarnContext(ctx, "Failed to cache webhook subscribers", "user_id", *userID, "error", err)
+	}
+	return wants
+}
+
+// ForgetClickSubscribers clears the cached answer of WantsClicks after the
+// user's endpoints change
+func ForgetClickSubscribers(ctx context.Context, rdb *redis.Client, userID int64) {
+	if err := rdb.Del(ctx, clickSubscribersKey(userID)).Err(); err != nil {
+		slog.WarnContext(ctx, "Failed to clear cached webhook subscribers", "user_id", userID, "error", err)
+	}
+}
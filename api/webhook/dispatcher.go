@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"shawty-ur/api/health"
+	"shawty-ur/api/helper"
+	"shawty-ur/api/models"
+	"shawty-ur/api/safehttp"
+)
+
+const (
+	// pollInterval is how often the outbox is checked for due deliveries
+	pollInterval = 5 * time.Second
+	// staleAfter is how long the dispatcher may go without progress before
+	// readiness reports it stale
+	staleAfter = 5 * time.Minute
+	// batchSize is how many deliveries are claimed per poll
+	batchSize = 50
+	// concurrency is how many deliveries are sent at once
+	concurrency = 10
+	// claimLease keeps other replicas off a claimed delivery while it's being sent
+	claimLease = 2 * time.Minute
+
+	// retryBase is the delay after the first failed attempt, doubled on every
+	// further failure up to retryMax
+	retryBase = 30 * time.Second
+	retryMax  = 6 * time.Hour
+	// maxAttempts is when a delivery is given up on, after about a day and a half
+	maxAttempts = 15
+)
+
+// userAgent identifies deliveries to receivers
+const userAgent = "shawty-ur-webhooks/1.0"
+
+// Dispatcher sends queued deliveries from the outbox, retrying failures with
+// exponential backoff, and queues link.expired events as links expire
+type Dispatcher struct {
+	webhooks  *helper.WebhookStore
+	links     *helper.LinkStore
+	db        *sql.DB
+	client    *http.Client
+	heartbeat *health.Heartbeat
+}
+
+// NewDispatcher creates a dispatcher for the outbox stored in db
+func NewDispatcher(db *sql.DB) *Dispatcher {
+	return &Dispatcher{
+		webhooks:  helper.NewWebhookStore(db),
+		links:     helper.NewLinkStore(db),
+		db:        db,
+		client:    newClient(),
+		heartbeat: health.NewHeartbeat("webhooks", staleAfter),
+	}
+}
+
+// newClient creates the client deliveries are sent with. It only connects to
+// public addresses and doesn't follow redirects, which would silently drop
+// the body and signature.
+func newClient() *http.Client {
+	client := safehttp.NewClient(10 * time.Second)
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return client
+}
+
+// Heartbeat tells whether the dispatcher is still making progress
+func (d *Dispatcher) Heartbeat() *health.Heartbeat {
+	return d.heartbeat
+}
+
+// Run delivers webhooks until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.publishExpired(ctx)
+		for {
+			n, err := d.deliverDue(ctx)
+			if err == nil {
+				d.heartbeat.Beat()
+			}
+			if err != nil || n < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishExpired queues link.expired for links that expired since the last poll
+func (d *Dispatcher) publishExpired(ctx context.Context) {
+	links, err := d.links.ClaimExpiredLinks(ctx, batchSize)
+	if err != nil {
+		return
+	}
+	for _, link := range links {
+		Publish(ctx, d.db, link.UserID, models.EventLinkExpired, map[string]any{"link": link})
+	}
+}
+
+// deliverDue sends one batch of due deliveries and returns how many there were
+func (d *Dispatcher) deliverDue(ctx context.Context) (int, error) {
+	deliveries, err := d.webhooks.ClaimDeliveries(ctx, batchSize, claimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			d.deliver(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// deliver sends a single delivery and records the outcome
+func (d *Dispatcher) deliver(ctx context.Context, delivery *helper.PendingDelivery) {
+	statusCode, err := d.send(ctx, delivery)
+	if err == nil {
+		_ = d.webhooks.MarkDelivered(ctx, delivery.ID, statusCode)
+		return
+	}
+
+	var status *int
+	if statusCode > 0 {
+		status = &statusCode
+	}
+	var next *time.Time
+	if delivery.Attempts < maxAttempts {
+		at := time.Now().Add(retryDelay(delivery.Attempts))
+		next = &at
+	}
+
+	slog.WarnContext(ctx, "Webhook delivery failed", "delivery_id", delivery.ID, "endpoint_id", delivery.EndpointID,
+		"event", delivery.Event, "attempt", delivery.Attempts, "error", err, "giving_up", next == nil)
+	_ = d.webhooks.MarkAttemptFailed(ctx, delivery.ID, status, err.Error(), next)
+}
+
+// send posts a delivery, treating anything but a 2xx response as a failure
+func (d *Dispatcher) send(ctx context.Context, delivery *helper.PendingDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, time.Now(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryDelay is how long to wait after the given number of failed attempts
+func retryDelay(attempts int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempts && delay < retryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMax)
+}
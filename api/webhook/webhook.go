@@ -0,0 +1,61 @@
+// Package webhook delivers signed event notifications to user-configured endpoints
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"shawty-ur/api/helper"
+)
+
+// Request headers sent with every delivery
+const (
+	EventHeader     = "X-Shawty-Event"
+	DeliveryHeader  = "X-Shawty-Delivery"
+	SignatureHeader = "X-Shawty-Signature"
+)
+
+// Payload is the JSON body of a delivery
+type Payload struct {
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+// Sign computes the signature header value of a delivery body:
+// "t=<unix timestamp>,v1=<hex HMAC-SHA256 of "<timestamp>.<body>">".
+// Receivers recompute the HMAC with their endpoint secret and should reject
+// old timestamps to prevent replays.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publish queues event for every endpoint of userID subscribed to it. Events
+// of anonymous links (nil userID) go nowhere. Failures are logged, never
+// returned, so publishing can't break the action that triggered it.
+func Publish(ctx context.Context, db *sql.DB, userID *int64, event string, data any) {
+	if userID == nil {
+		return
+	}
+
+	body, err := json.Marshal(Payload{Event: event, OccurredAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to encode webhook payload", "event", event, "error", err)
+		return
+	}
+
+	webhookStore := helper.NewWebhookStore(db)
+	_ = webhookStore.Enqueue(ctx, *userID, event, body)
+}
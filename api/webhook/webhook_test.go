@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"shawty-ur/api/models"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"link.created"}`)
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	want := "t=1767225600,v1=7284cfa9927f7c4e5f75f704b2c9fd721606ac2dd8d6c8ac00b27e535990ea18"
+	if got := Sign("whsec_test", at, body); got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+
+	if Sign("whsec_other", at, body) == want {
+		t.Error("Sign() ignores the secret")
+	}
+	if Sign("whsec_test", at.Add(time.Second), body) == want {
+		t.Error("Sign() ignores the timestamp")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, retryBase},
+		{2, 2 * retryBase},
+		{3, 4 * retryBase},
+		{50, retryMax},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestNewClickLeavesOutVisitorDetails(t *testing.T) {
+	click := &models.Click{
+		IPAddress:      "203.0.113.7",
+		IPHash:         "abc",
+		UserAgent:      "Mozilla/5.0",
+		Referrer:       "https://www.reddit.com/r/golang/comments/xyz",
+		City:           "Berlin",
+		Country:        "DE",
+		ReferrerDomain: "reddit.com",
+		Device:         "mobile",
+		ClickedAt:      time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	body, err := json.Marshal(NewClick(click))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leaked := range []string{click.IPAddress, click.IPHash, click.UserAgent, click.Referrer, click.City} {
+		if strings.Contains(string(body), leaked) {
+			t.Errorf("NewClick() payload %s contains %q", body, leaked)
+		}
+	}
+	if !strings.Contains(string(body), `"referrer_domain":"reddit.com"`) {
+		t.Errorf("NewClick() payload %s lacks the referrer domain", body)
+	}
+}
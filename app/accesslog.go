@@ -0,0 +1,59 @@
+package app
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"shawty-ur/api/logging"
+	"shawty-ur/api/privacy"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// probePaths are polled every few seconds by orchestrators, so their
+// successful requests are only logged at debug level
+var probePaths = map[string]bool{
+	"/livez":  true,
+	"/readyz": true,
+}
+
+// accessLog logs every request once it has been served, as a structured
+// record with its request ID, user and trace. Client addresses are
+// anonymized per the server's privacy mode, and only the path is logged
+// since query strings can carry OAuth codes and other secrets.
+func (app *Application) accessLog() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			r = r.WithContext(logging.WithRequestUser(r.Context()))
+
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				level := slog.LevelInfo
+				if status >= http.StatusInternalServerError {
+					level = slog.LevelError
+				} else if probePaths[r.URL.Path] {
+					level = slog.LevelDebug
+				}
+				app.Logger.LogAttrs(r.Context(), level, "Request served",
+					slog.String("method", r.Method),
+					slog.String("host", r.Host),
+					slog.String("path", r.URL.Path),
+					slog.String("proto", r.Proto),
+					slog.Int("status", status),
+					slog.Int("bytes", ww.BytesWritten()),
+					slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+					slog.String("remote_addr", privacy.AnonymizeAddr(r.RemoteAddr, app.Config.Privacy.Mode)),
+					slog.String("user_agent", r.UserAgent()),
+				)
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
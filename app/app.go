@@ -6,6 +6,12 @@ import (
 	"net/http"
 
 	"shawty-ur/api/auth"
+	"shawty-ur/api/bots"
+	"shawty-ur/api/deeplink"
+	"shawty-ur/api/geoip"
+	"shawty-ur/api/health"
+	appmiddleware "shawty-ur/api/middleware"
+	"shawty-ur/api/referrer"
 	"shawty-ur/config"
 
 	"github.com/go-chi/chi/v5"
@@ -20,12 +26,19 @@ type RouteRegistrar func(r chi.Router, app *Application)
 // Application holds the application state and dependencies
 type Application struct {
 	Config              config.Config
+	Logger              *slog.Logger
 	DbConnector         *sql.DB
 	RedisClient         *redis.Client
 	OAuthConfig         *auth.OAuthConfig
 	SessionStore        *auth.SessionStore
+	GeoIP               *geoip.Resolver
+	AppLinks            deeplink.Associations
+	Bots                *bots.Classifier
+	Referrers           *referrer.Rules
+	Workers             []*health.Heartbeat // Background workers checked by readiness
 	routeRegistrars     []RouteRegistrar
 	soloRouteRegistrars []RouteRegistrar
+	internalRegistrars  []RouteRegistrar
 }
 
 func (app *Application) RegisterRoutes(registrars ...RouteRegistrar) {
@@ -35,16 +48,21 @@ func (app *Application) RegisterSoloRoutes(registrars ...RouteRegistrar) {
 	app.soloRouteRegistrars = append(app.soloRouteRegistrars, registrars...)
 }
 
+// RegisterInternalRoutes registers routes served next to /metrics on the
+// internal listener only
+func (app *Application) RegisterInternalRoutes(registrars ...RouteRegistrar) {
+	app.internalRegistrars = append(app.internalRegistrars, registrars...)
+}
+
 // Mount sets up all the routes and middleware for the application
 func (app *Application) Mount() *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(middleware.RealIP)
+	r.Use(appmiddleware.TracingMiddleware)
 	r.Use(middleware.RequestID)
-	r.Use(middleware.Logger)
+	r.Use(app.accessLog())
 	r.Use(middleware.Recoverer)
-
-	// Health check at root level (must come before catch-all routes)
-	r.Get("/health", app.healthCheckHandler)
+	r.Use(appmiddleware.PrometheusMiddleware)
 
 	// Register versioned API routes
 	r.Route("/api/v1", func(r chi.Router) {
@@ -54,34 +72,36 @@ func (app *Application) Mount() *chi.Mux {
 	})
 
 	// Register solo routes (like /:url) directly at root level
-	// These must come AFTER specific routes like /health to avoid conflicts
-	slog.Info("Registering solo routes", "count", len(app.soloRouteRegistrars))
+	// These must come AFTER specific routes like /livez to avoid conflicts
+	app.Logger.Debug("Registering solo routes", "count", len(app.soloRouteRegistrars))
 	for _, registrar := range app.soloRouteRegistrars {
 		registrar(r, app)
 	}
 
 	// Print all registered routes for debugging
 	chi.Walk(r, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
-		slog.Info("Registered route", "method", method, "route", route)
+		app.Logger.Debug("Registered route", "method", method, "route", route)
 		return nil
 	})
 
 	return r
 }
 
-// healthCheckHandler provides a quick health check at root level
-func (app *Application) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
-}
-
-// Run starts the HTTP server
+// Run starts the HTTP server, or the HTTPS server when TLS is enabled
 func (app *Application) Run(mux *chi.Mux) error {
 	srv := &http.Server{
 		Addr:    app.Config.Addr,
 		Handler: mux,
 	}
 
-	slog.Info("server started at ", app.Config.Addr, "default")
+	if app.Config.Metrics.Addr != "" {
+		go app.serveMetrics()
+	}
+
+	if app.Config.TLSConfig.Enabled {
+		return app.runTLS(srv)
+	}
+
+	app.Logger.Info("Server started", "addr", app.Config.Addr)
 	return srv.ListenAndServe()
 }
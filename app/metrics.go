@@ -0,0 +1,31 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// serveMetrics serves Prometheus metrics, and the internal routes, on their
+// own listener, so they can be reached on an internal address without being
+// exposed next to the short links. A failing metrics listener is logged but
+// doesn't stop the server.
+func (app *Application) serveMetrics() {
+	r := chi.NewRouter()
+	r.Handle("/metrics", promhttp.Handler())
+	for _, registrar := range app.internalRegistrars {
+		registrar(r, app)
+	}
+
+	srv := &http.Server{
+		Addr:              app.Config.Metrics.Addr,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	app.Logger.Info("Metrics listener started", "addr", srv.Addr)
+	if err := srv.ListenAndServe(); err != nil {
+		app.Logger.Error("Metrics listener stopped", "error", err)
+	}
+}
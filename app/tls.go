@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"shawty-ur/api/helper"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// certManager builds the ACME certificate manager from the TLS config.
+// Certificates are cached in Postgres so all replicas share them.
+func (app *Application) certManager() (*autocert.Manager, error) {
+	cfg := app.Config.TLSConfig
+
+	client := &acme.Client{DirectoryURL: cfg.DirectoryURL}
+	if client.DirectoryURL == "" {
+		client.DirectoryURL = autocert.DefaultACMEDirectory
+	}
+
+	// A local ACME test server such as Pebble serves its API with a
+	// certificate from its own CA, which has to be trusted explicitly
+	if cfg.CACertPath != "" {
+		pem, err := os.ReadFile(cfg.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ACME CA certificate: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CACertPath)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+		client.HTTPClient = &http.Client{Transport: transport}
+	}
+
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      helper.NewCertCache(app.DbConnector),
+		HostPolicy: app.hostPolicy,
+		Email:      cfg.Email,
+		Client:     client,
+	}, nil
+}
+
+// hostPolicy only lets certificates be requested for the configured hosts and
+// verified branded domains, so random SNI names can't exhaust ACME rate limits
+func (app *Application) hostPolicy(ctx context.Context, host string) error {
+	host = helper.NormalizeHostname(host)
+	if slices.Contains(app.Config.TLSConfig.Hosts, host) {
+		return nil
+	}
+
+	domainStore := helper.NewDomainStore(app.DbConnector)
+	domain, err := domainStore.GetVerifiedDomain(ctx, host)
+	if err != nil {
+		return err
+	}
+	if domain == nil {
+		return fmt.Errorf("host %q is not a verified domain", host)
+	}
+	return nil
+}
+
+// httpsRedirect sends plain HTTP requests to the same URL over HTTPS on the
+// port of tlsAddr
+func httpsRedirect(tlsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(tlsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := helper.NormalizeHostname(r.Host)
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// runTLS serves srv over HTTPS with ACME certificates, alongside a plain HTTP
+// listener that answers ACME http-01 challenges and redirects everything else
+// to HTTPS. tls-alpn-01 challenges are answered on the HTTPS listener.
+func (app *Application) runTLS(srv *http.Server) error {
+	manager, err := app.certManager()
+	if err != nil {
+		return err
+	}
+	srv.TLSConfig = manager.TLSConfig()
+
+	redirect := &http.Server{
+		Addr:              app.Config.TLSConfig.HTTPAddr,
+		Handler:           manager.HTTPHandler(httpsRedirect(srv.Addr)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 2)
+	go func() {
+		app.Logger.Info("HTTP redirect listener started", "addr", redirect.Addr)
+		errs <- redirect.ListenAndServe()
+	}()
+	go func() {
+		app.Logger.Info("HTTPS server started", "addr", srv.Addr, "acme_directory", manager.Client.DirectoryURL)
+		errs <- srv.ListenAndServeTLS("", "")
+	}()
+
+	// Either listener failing takes the other one down with it
+	err = <-errs
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return errors.Join(err, redirect.Shutdown(ctx), srv.Shutdown(ctx))
+}
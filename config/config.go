@@ -1,6 +1,11 @@
 package config
 
-import "shawty-ur/api/utils/db"
+import (
+	"log/slog"
+	"time"
+
+	"shawty-ur/api/utils/db"
+)
 
 // RedisConfig holds Redis configuration
 type RedisConfig struct {
@@ -9,10 +14,74 @@ type RedisConfig struct {
 	DB       int
 }
 
+// GeoIPConfig holds the location of the offline GeoIP database
+type GeoIPConfig struct {
+	DbPath         string        // MaxMind-format .mmdb file; empty disables geo lookups
+	ReloadInterval time.Duration // How often to check the file for changes
+}
+
+// LinkHealthConfig holds the settings of the destination health monitor
+type LinkHealthConfig struct {
+	Interval    time.Duration // How often each link is checked; 0 disables the monitor
+	Timeout     time.Duration // Per-request timeout
+	Concurrency int           // Checks in flight at once
+	PerHost     int           // Checks in flight at once against a single host
+}
+
+// AnalyticsConfig holds how long each tier of click analytics is kept. A
+// retention of 0 keeps the tier forever.
+type AnalyticsConfig struct {
+	RawRetention    time.Duration // Individual clicks in url_analytics
+	HourlyRetention time.Duration // Hourly rollups
+	DailyRetention  time.Duration // Daily rollups
+}
+
+// PrivacyConfig holds the server-wide visitor privacy settings
+type PrivacyConfig struct {
+	Mode   string // How visitor IPs are anonymized; links can only make it stricter
+	OptOut string // What happens to clicks sent with DNT: 1 or Sec-GPC: 1
+}
+
+// MetricsConfig holds the settings of the Prometheus metrics listener
+type MetricsConfig struct {
+	Addr string // Listener serving /metrics, apart from the public one; empty disables it
+}
+
+// TracingConfig holds the OpenTelemetry tracing settings
+type TracingConfig struct {
+	Exporter    string  // Where spans go: otlp, stdout or none
+	ServiceName string  // service.name of the spans
+	SampleRatio float64 // Share of new traces sampled; traces started upstream follow their parent
+}
+
+// LogConfig holds how log records are written
+type LogConfig struct {
+	Format string     // json or text
+	Level  slog.Level // Records below it are dropped
+}
+
+// TLSConfig holds the settings for serving HTTPS with ACME certificates
+type TLSConfig struct {
+	Enabled      bool     // Serve HTTPS on Addr, with certificates from ACME
+	HTTPAddr     string   // Plain HTTP listener for ACME challenges and redirects to HTTPS
+	Email        string   // Contact address registered with the ACME account
+	DirectoryURL string   // ACME directory; empty means Let's Encrypt
+	CACertPath   string   // Extra root CA trusted when talking to the ACME server, e.g. Pebble's
+	Hosts        []string // Hostnames always allowed besides verified branded domains
+}
+
 // Config holds the application configuration
 type Config struct {
 	Addr        string
 	JwtSecret   string
 	DbConfig    db.DbConfig
 	RedisConfig RedisConfig
+	GeoIPConfig GeoIPConfig
+	TLSConfig   TLSConfig
+	LinkHealth  LinkHealthConfig
+	Analytics   AnalyticsConfig
+	Privacy     PrivacyConfig
+	Metrics     MetricsConfig
+	Tracing     TracingConfig
+	Log         LogConfig
 }
@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Searchable metadata for links
+ALTER TABLE urls ADD COLUMN title TEXT; -- <title> of the destination page, fetched after creation
+ALTER TABLE urls ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE urls ADD COLUMN notes TEXT;
+ALTER TABLE urls ADD COLUMN search_vector TSVECTOR;
+
+-- array_to_string is not immutable, so the vector is kept up to date by a trigger
+-- instead of a generated column
+CREATE OR REPLACE FUNCTION urls_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('simple', COALESCE(NEW.short_code, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('simple', array_to_string(NEW.tags, ' ')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(NEW.original_url, '')), 'C') ||
+        setweight(to_tsvector('simple', COALESCE(NEW.notes, '')), 'D');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_urls_search_vector
+BEFORE INSERT OR UPDATE OF short_code, title, tags, original_url, notes ON urls
+FOR EACH ROW EXECUTE FUNCTION urls_search_vector_update();
+
+-- Backfill existing rows
+UPDATE urls SET short_code = short_code;
+
+CREATE INDEX idx_urls_search_vector ON urls USING GIN(search_vector);
+CREATE INDEX idx_urls_tags ON urls USING GIN(tags);
+
+-- Trigram indexes back the partial-match fallback (ILIKE '%q%')
+CREATE INDEX idx_urls_short_code_trgm ON urls USING GIN(short_code gin_trgm_ops);
+CREATE INDEX idx_urls_original_url_trgm ON urls USING GIN(original_url gin_trgm_ops);
+CREATE INDEX idx_urls_title_trgm ON urls USING GIN(title gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_urls_title_trgm;
+DROP INDEX IF EXISTS idx_urls_original_url_trgm;
+DROP INDEX IF EXISTS idx_urls_short_code_trgm;
+DROP INDEX IF EXISTS idx_urls_tags;
+DROP INDEX IF EXISTS idx_urls_search_vector;
+DROP TRIGGER IF EXISTS trg_urls_search_vector ON urls;
+DROP FUNCTION IF EXISTS urls_search_vector_update();
+ALTER TABLE urls DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE urls DROP COLUMN IF EXISTS notes;
+ALTER TABLE urls DROP COLUMN IF EXISTS tags;
+ALTER TABLE urls DROP COLUMN IF EXISTS title;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Per-link redirect options: forward the visitor's query string and/or the
+-- path after the short code (/abc123/extra/path) to the destination
+ALTER TABLE urls ADD COLUMN forward_query BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE urls ADD COLUMN forward_path BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE urls DROP COLUMN IF EXISTS forward_path;
+ALTER TABLE urls DROP COLUMN IF EXISTS forward_query;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Ordered conditional redirect rules evaluated on resolve, e.g.
+-- [{"os": ["ios"], "destination": "https://apps.apple.com/..."}]
+ALTER TABLE urls ADD COLUMN routing_rules JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE urls DROP COLUMN IF EXISTS routing_rules;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Weighted destinations for A/B split-test links
+CREATE TABLE IF NOT EXISTS link_variants (
+    id BIGSERIAL PRIMARY KEY,
+    url_id BIGINT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    destination TEXT NOT NULL,
+    weight INTEGER NOT NULL CHECK (weight >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (url_id, name)
+);
+
+CREATE INDEX idx_link_variants_url_id ON link_variants(url_id);
+
+-- Which variant a click was sent to (NULL for links without variants)
+ALTER TABLE url_analytics ADD COLUMN variant_id BIGINT REFERENCES link_variants(id) ON DELETE SET NULL;
+CREATE INDEX idx_url_analytics_variant_id ON url_analytics(variant_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_url_analytics_variant_id;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS variant_id;
+DROP INDEX IF EXISTS idx_link_variants_url_id;
+DROP TABLE IF EXISTS link_variants;
+-- +goose StatementEnd
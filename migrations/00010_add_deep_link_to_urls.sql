@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- App URLs opened through the HTML bridge page for mobile visitors, e.g.
+-- {"ios": "myapp://item/42", "android": "myapp://item/42"}
+ALTER TABLE urls ADD COLUMN deep_link JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE urls DROP COLUMN IF EXISTS deep_link;
+-- +goose StatementEnd
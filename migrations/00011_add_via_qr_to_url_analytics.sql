@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Clicks that came from scanning the link's QR code (?qr=1 marker)
+ALTER TABLE url_analytics ADD COLUMN via_qr BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS via_qr;
+-- +goose StatementEnd
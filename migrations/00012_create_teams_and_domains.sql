@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS teams (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS team_members (
+    team_id BIGINT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL DEFAULT 'member', -- 'owner' or 'member'
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (team_id, user_id)
+);
+
+CREATE INDEX idx_team_members_user_id ON team_members(user_id);
+
+-- Branded short-link domains, usable once the team proves control over DNS
+CREATE TABLE IF NOT EXISTS domains (
+    id BIGSERIAL PRIMARY KEY,
+    team_id BIGINT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    hostname VARCHAR(253) NOT NULL,
+    verification_token VARCHAR(64) NOT NULL,
+    verified_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A hostname belongs to the team that verified it. Until then any team may
+-- claim it, once, so an unverified claim can't lock the real owner out.
+CREATE UNIQUE INDEX idx_domains_verified_hostname ON domains(hostname) WHERE verified_at IS NOT NULL;
+CREATE UNIQUE INDEX idx_domains_team_hostname ON domains(team_id, hostname);
+
+-- Short codes are unique per domain; NULL is the default (DOMAIN) domain
+ALTER TABLE urls ADD COLUMN domain_id BIGINT REFERENCES domains(id) ON DELETE CASCADE;
+ALTER TABLE urls DROP CONSTRAINT IF EXISTS urls_short_code_key;
+CREATE UNIQUE INDEX idx_urls_domain_short_code ON urls(COALESCE(domain_id, 0), short_code);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_urls_domain_short_code;
+DELETE FROM urls WHERE domain_id IS NOT NULL;
+ALTER TABLE urls ADD CONSTRAINT urls_short_code_key UNIQUE (short_code);
+ALTER TABLE urls DROP COLUMN IF EXISTS domain_id;
+DROP INDEX IF EXISTS idx_domains_team_hostname;
+DROP INDEX IF EXISTS idx_domains_verified_hostname;
+DROP TABLE IF EXISTS domains;
+DROP INDEX IF EXISTS idx_team_members_user_id;
+DROP TABLE IF EXISTS team_members;
+DROP TABLE IF EXISTS teams;
+-- +goose StatementEnd
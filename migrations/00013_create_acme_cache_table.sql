@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- ACME account key and certificates of branded domains, shared by all replicas
+CREATE TABLE IF NOT EXISTS acme_cache (
+    key VARCHAR(255) PRIMARY KEY,
+    data BYTEA NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS acme_cache;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Outcome of the latest destination check, see api/linkhealth
+ALTER TABLE urls ADD COLUMN health_status_code INT;
+ALTER TABLE urls ADD COLUMN health_latency_ms BIGINT;
+ALTER TABLE urls ADD COLUMN health_error TEXT;
+ALTER TABLE urls ADD COLUMN health_checked_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE urls ADD COLUMN health_failures INT NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN broken BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE urls ADD COLUMN health_next_check_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX idx_urls_health_next_check_at ON urls(health_next_check_at);
+CREATE INDEX idx_urls_broken ON urls(user_id) WHERE broken;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_urls_broken;
+DROP INDEX IF EXISTS idx_urls_health_next_check_at;
+ALTER TABLE urls DROP COLUMN IF EXISTS health_next_check_at;
+ALTER TABLE urls DROP COLUMN IF EXISTS broken;
+ALTER TABLE urls DROP COLUMN IF EXISTS health_failures;
+ALTER TABLE urls DROP COLUMN IF EXISTS health_checked_at;
+ALTER TABLE urls DROP COLUMN IF EXISTS health_error;
+ALTER TABLE urls DROP COLUMN IF EXISTS health_latency_ms;
+ALTER TABLE urls DROP COLUMN IF EXISTS health_status_code;
+-- +goose StatementEnd
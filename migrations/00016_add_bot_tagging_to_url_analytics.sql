@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Clicks from crawlers, link unfurlers and prefetches, with what gave them away
+ALTER TABLE url_analytics ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE url_analytics ADD COLUMN bot_reason VARCHAR(100);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS bot_reason;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS is_bot;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- User agents parsed at ingest, so breakdowns don't have to re-parse raw strings
+ALTER TABLE url_analytics ADD COLUMN browser VARCHAR(32);
+ALTER TABLE url_analytics ADD COLUMN browser_version VARCHAR(16);
+ALTER TABLE url_analytics ADD COLUMN os VARCHAR(32);
+ALTER TABLE url_analytics ADD COLUMN os_version VARCHAR(16);
+ALTER TABLE url_analytics ADD COLUMN device_type VARCHAR(16);
+CREATE INDEX idx_url_analytics_url_id_device_type ON url_analytics(url_id, device_type);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_url_analytics_url_id_device_type;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS device_type;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS os_version;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS os;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS browser_version;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS browser;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Where a click came from: the normalized referrer and the campaign it was tagged with
+ALTER TABLE url_analytics ADD COLUMN referrer_domain VARCHAR(255);
+ALTER TABLE url_analytics ADD COLUMN referrer_category VARCHAR(16);
+ALTER TABLE url_analytics ADD COLUMN utm_source VARCHAR(255);
+ALTER TABLE url_analytics ADD COLUMN utm_medium VARCHAR(255);
+ALTER TABLE url_analytics ADD COLUMN utm_campaign VARCHAR(255);
+ALTER TABLE url_analytics ADD COLUMN utm_term VARCHAR(255);
+ALTER TABLE url_analytics ADD COLUMN utm_content VARCHAR(255);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS utm_content;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS utm_term;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS utm_campaign;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS utm_medium;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS utm_source;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS referrer_category;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS referrer_domain;
+-- +goose StatementEnd
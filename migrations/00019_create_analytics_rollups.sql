@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Click counts per link, hour and dimension value, aggregated from url_analytics.
+-- dimension '' (value '') holds the link's totals; other dimensions are
+-- url_analytics columns, with '' standing for NULL, or 'variant' keyed by variant id.
+CREATE TABLE IF NOT EXISTS analytics_rollups_hourly (
+    url_id BIGINT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    dimension VARCHAR(32) NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    clicks BIGINT NOT NULL,
+    bot_clicks BIGINT NOT NULL,
+    qr_clicks BIGINT NOT NULL,
+    PRIMARY KEY (url_id, bucket, dimension, value)
+);
+CREATE INDEX idx_analytics_rollups_hourly_bucket ON analytics_rollups_hourly(bucket);
+
+-- The same per UTC day, aggregated from the hourly rollups
+CREATE TABLE IF NOT EXISTS analytics_rollups_daily (
+    url_id BIGINT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    dimension VARCHAR(32) NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    clicks BIGINT NOT NULL,
+    bot_clicks BIGINT NOT NULL,
+    qr_clicks BIGINT NOT NULL,
+    PRIMARY KEY (url_id, bucket, dimension, value)
+);
+CREATE INDEX idx_analytics_rollups_daily_bucket ON analytics_rollups_daily(bucket);
+
+-- How far the rollups are complete: every click before watermark is aggregated
+CREATE TABLE IF NOT EXISTS analytics_rollup_state (
+    name VARCHAR(32) PRIMARY KEY,
+    watermark TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS analytics_rollup_state;
+DROP TABLE IF EXISTS analytics_rollups_daily;
+DROP TABLE IF EXISTS analytics_rollups_hourly;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Per-link privacy mode ('off', 'truncate' or 'hash'); NULL follows the server setting
+ALTER TABLE urls ADD COLUMN privacy_mode VARCHAR(16);
+-- Salted per-day hash stored instead of ip_address in the 'hash' privacy mode
+ALTER TABLE url_analytics ADD COLUMN ip_hash VARCHAR(32);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS ip_hash;
+ALTER TABLE urls DROP COLUMN IF EXISTS privacy_mode;
+-- +goose StatementEnd
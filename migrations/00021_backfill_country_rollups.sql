@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Clicks are now also rolled up per country. Move the watermark back to the
+-- oldest raw click still kept, so the rollups from there on are rebuilt with
+-- the new dimension; older rollups stay as they are.
+UPDATE analytics_rollup_state
+SET watermark = LEAST(watermark, (SELECT date_trunc('hour', MIN(clicked_at), 'UTC') FROM url_analytics)),
+    updated_at = NOW()
+WHERE name = 'clicks';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM analytics_rollups_hourly WHERE dimension = 'country';
+DELETE FROM analytics_rollups_daily WHERE dimension = 'country';
+-- +goose StatementEnd
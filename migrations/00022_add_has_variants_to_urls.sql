@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Set while a link has split-test variants, so redirects of all other links
+-- don't have to look for them
+ALTER TABLE urls ADD COLUMN has_variants BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE urls SET has_variants = TRUE WHERE id IN (SELECT DISTINCT url_id FROM link_variants);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE urls DROP COLUMN IF EXISTS has_variants;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+// Package migrations embeds the goose migrations, so the server knows which
+// schema version it was built for
+package migrations
+
+import "embed"
+
+// FS holds the migration files
+//
+//go:embed *.sql
+var FS embed.FS